@@ -0,0 +1,74 @@
+package rpcmux
+
+import (
+	"context"
+	"net/rpc"
+	"time"
+)
+
+// Batch groups several calls so that they are written to the connection back to back
+// and their replies are awaited together, paying the round-trip latency once.
+// A Batch is not safe for concurrent use, and should be executed only once.
+type Batch struct {
+	d     *RPCDuplex
+	calls []*rpc.Call
+	errs  []error
+}
+
+// Batch returns an empty Batch of calls to be made over d.
+func (d *RPCDuplex) Batch() *Batch {
+	return &Batch{d: d}
+}
+
+// Add queues a call to method. reply is populated when the Batch is executed.
+func (b *Batch) Add(method string, args, reply interface{}) {
+	b.calls = append(b.calls, &rpc.Call{ServiceMethod: method, Args: args, Reply: reply})
+}
+
+// Execute sends all queued calls and waits until every reply has arrived or ctx is done.
+// It returns ctx.Err() if ctx is done first, otherwise the first error of the individual calls.
+// The error of every call is available from Errors afterwards. Each call is counted in
+// MethodStats and the call trace like a call made with Call.
+func (b *Batch) Execute(ctx context.Context) error {
+	if err := b.d.beginCall(); err != nil {
+		return err
+	}
+	defer b.d.endCall()
+
+	start := time.Now()
+	done := make(chan *rpc.Call, len(b.calls))
+	index := make(map[*rpc.Call]int, len(b.calls))
+	for i, c := range b.calls {
+		index[b.d.Go(c.ServiceMethod, c.Args, c.Reply, done)] = i
+	}
+
+	b.errs = make([]error, len(b.calls))
+	for remaining := len(b.calls); remaining > 0; remaining-- {
+		select {
+		case c := <-done:
+			err := b.d.callError(c.Error)
+			b.errs[index[c]] = err
+			delete(index, c)
+			b.d.record(c.ServiceMethod, start, err)
+		case <-ctx.Done():
+			for c, i := range index {
+				b.errs[i] = ctx.Err()
+				b.d.record(c.ServiceMethod, start, ctx.Err())
+			}
+			return ctx.Err()
+		}
+	}
+
+	for _, err := range b.errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Errors returns the error of each call in the order they were added.
+// It returns nil if the Batch has not been executed.
+func (b *Batch) Errors() []error {
+	return b.errs
+}
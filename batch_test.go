@@ -0,0 +1,44 @@
+package rpcmux
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBatchExecute(t *testing.T) {
+	a, _ := newPipe(t, WithCallTrace(16))
+
+	names := []string{"Anto", "Bea", "Cy"}
+	replies := make([]Person, len(names))
+	batch := a.Batch()
+	for i, name := range names {
+		batch.Add("RPCMethod.SayHello", Person{Name: name}, &replies[i])
+	}
+	batch.Add("RPCMethod.Missing", Person{}, &Person{})
+
+	if err := batch.Execute(context.Background()); err == nil {
+		t.Fatal("Execute succeeded with a call to a missing method")
+	}
+	for i, name := range names {
+		if replies[i].Name != name {
+			t.Errorf("reply %d = %q, want %q", i, replies[i].Name, name)
+		}
+		if err := batch.Errors()[i]; err != nil {
+			t.Errorf("error %d = %v", i, err)
+		}
+	}
+	if batch.Errors()[len(names)] == nil {
+		t.Error("missing method call has no error")
+	}
+
+	stats := a.MethodStats()
+	if got := stats["RPCMethod.SayHello"]; got.Calls != 3 || got.Errors != 0 {
+		t.Errorf("SayHello stats = %+v, want 3 calls and no errors", got)
+	}
+	if got := stats["RPCMethod.Missing"]; got.Calls != 1 || got.Errors != 1 {
+		t.Errorf("Missing stats = %+v, want 1 call and 1 error", got)
+	}
+	if got := len(a.DumpCallTrace()); got != 4 {
+		t.Errorf("trace has %d entries, want 4", got)
+	}
+}
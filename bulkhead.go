@@ -0,0 +1,79 @@
+package rpcmux
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// ErrBulkheadFull is the error of calls rejected because their client has too many calls in flight.
+var ErrBulkheadFull = errors.New("rpcmux: too many concurrent calls from client")
+
+// bulkheadFullMethod is the reserved method that requests rejected by a bulkhead are routed to.
+const bulkheadFullMethod = introspectionServiceName + ".BulkheadFull"
+
+// WithBulkhead limits the requests served concurrently for each client IP address to maxPerClient,
+// so that a single slow client cannot take all the server goroutines. Requests beyond the limit
+// are answered with ErrBulkheadFull without calling their handler. RPCDuplex.Call returns that
+// error itself; calls made with the embedded rpc.Client get a rpc.ServerError with the same text.
+//
+// The client address is the one read by WithPROXYProtocolV2 if any, else the remote address of
+// the connection. Duplexes created with the same Option, such as those accepted by a
+// DuplexListener, share the budget of each address.
+func WithBulkhead(maxPerClient int) Option {
+	b := &bulkhead{max: maxPerClient, inflight: make(map[string]int)}
+	return func(d *RPCDuplex) {
+		d.bulkhead = b
+	}
+}
+
+// bulkhead counts the requests in flight for each client address.
+type bulkhead struct {
+	max int
+
+	mu       sync.Mutex
+	inflight map[string]int
+}
+
+// acquire reports whether a request from client may be served, counting it if so.
+func (b *bulkhead) acquire(client string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.inflight[client] >= b.max {
+		return false
+	}
+	b.inflight[client]++
+	return true
+}
+
+// release uncounts a request from client admitted by acquire.
+func (b *bulkhead) release(client string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.inflight[client]--; b.inflight[client] <= 0 {
+		delete(b.inflight, client)
+	}
+}
+
+// clientHost returns the IP address the bulkhead accounts the requests of d to.
+func (d *RPCDuplex) clientHost() string {
+	addr := d.ProxyAddr()
+	if addr == nil {
+		addr = d.RemoteAddr()
+	}
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// BulkheadFull answers the requests rejected by a bulkhead.
+func (s *introspection) BulkheadFull(_ struct{}, _ *struct{}) error {
+	return ErrBulkheadFull
+}
@@ -0,0 +1,30 @@
+package rpcmux
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBulkheadFull(t *testing.T) {
+	a, b := newPipe(t, WithBulkhead(1))
+	started := make(chan struct{}, 1)
+	if err := b.Register(&Sleeper{started: started}); err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- a.Call("Sleeper.Sleep", 100*time.Millisecond, &struct{}{}) }()
+	<-started
+
+	var reply Person
+	if err := a.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply); !errors.Is(err, ErrBulkheadFull) {
+		t.Errorf("Call beyond the bulkhead = %v, want ErrBulkheadFull", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Call within the bulkhead: %v", err)
+	}
+	if err := a.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply); err != nil {
+		t.Errorf("Call once the bulkhead has room: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package rpcmux
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChecksummingRoundTrip(t *testing.T) {
+	a, b := newPipe(t, WithChecksumming(true))
+	callBothWays(t, a, b, 4, strings.Repeat("x", 2*maxChecksumFrame+3))
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	var wire bytes.Buffer
+	w := &checksumConn{Conn: writerConn{&wire}}
+	if _, err := w.Write([]byte("hello, checksum")); err != nil {
+		t.Fatal(err)
+	}
+	frame := wire.Bytes()
+	frame[6] ^= 0x01 // flip a bit of the payload
+
+	local, remote := net.Pipe()
+	defer remote.Close()
+	go remote.Write(frame)
+
+	r := &checksumConn{Conn: local}
+	if _, err := r.Read(make([]byte, 64)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Read of a corrupted frame = %v, want ErrChecksumMismatch", err)
+	}
+	if _, err := local.Write([]byte{0}); err == nil {
+		t.Error("connection still open after a checksum mismatch")
+	}
+	if _, err := r.Read(make([]byte, 64)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("second Read = %v, want ErrChecksumMismatch", err)
+	}
+}
+
+func TestChecksumConcurrentWrites(t *testing.T) {
+	local, remote := net.Pipe()
+	w := &checksumConn{Conn: local}
+	r := &checksumConn{Conn: remote}
+
+	// Each write spans several frames; concurrent writers must not interleave them.
+	msgs := [][]byte{bytes.Repeat([]byte{'a'}, 2*maxChecksumFrame), bytes.Repeat([]byte{'b'}, 2*maxChecksumFrame)}
+	for _, msg := range msgs {
+		go w.Write(msg)
+	}
+	got := make([]byte, 4*maxChecksumFrame)
+	if _, err := io.ReadFull(r, got); err != nil {
+		t.Fatal(err)
+	}
+	first := got[:2*maxChecksumFrame]
+	if !bytes.Equal(first, msgs[0]) && !bytes.Equal(first, msgs[1]) {
+		t.Error("frames of concurrent writes were interleaved")
+	}
+	local.Close()
+	remote.Close()
+}
+
+// TestChecksumWholeMuxFrame writes the largest frame of the duplex: it must be sent as a
+// single checksummed frame.
+func TestChecksumWholeMuxFrame(t *testing.T) {
+	var wire bytes.Buffer
+	w := &checksumConn{Conn: writerConn{&wire}}
+	if _, err := w.Write(make([]byte, frameHeaderLen+maxFramePayload)); err != nil {
+		t.Fatal(err)
+	}
+	if want := 4 + frameHeaderLen + maxFramePayload + 4; wire.Len() != want {
+		t.Errorf("wrote %d bytes, want the %d of one checksummed frame", wire.Len(), want)
+	}
+}
+
+// TestChecksummingWithPROXYProtocol gives WithChecksumming before and after the PROXY
+// protocol options: the header must be sent and read without checksum either way.
+func TestChecksummingWithPROXYProtocol(t *testing.T) {
+	for _, first := range []bool{true, false} {
+		server, client := []Option{WithPROXYProtocolV2()}, []Option{WithSendPROXYProtocolV2()}
+		if first {
+			server = append([]Option{WithChecksumming(true)}, server...)
+			client = append([]Option{WithChecksumming(true)}, client...)
+		} else {
+			server = append(server, WithChecksumming(true))
+			client = append(client, WithChecksumming(true))
+		}
+		dl := listenTCP(t, server...)
+		serveAccepted(t, dl)
+
+		conn, err := net.Dial("tcp", dl.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := NewRPCDuplex(conn, client...)
+		defer d.Close()
+		var reply Person
+		if err := d.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply); err != nil {
+			t.Errorf("Call with WithChecksumming first %v: %v", first, err)
+		}
+	}
+}
+
+// writerConn is a net.Conn writing to an io.Writer. Only Write is used.
+type writerConn struct {
+	io.Writer
+}
+
+func (writerConn) Read([]byte) (int, error)           { return 0, io.EOF }
+func (writerConn) Close() error                       { return nil }
+func (writerConn) LocalAddr() net.Addr                { return nil }
+func (writerConn) RemoteAddr() net.Addr               { return nil }
+func (writerConn) SetDeadline(_ time.Time) error      { return nil }
+func (writerConn) SetReadDeadline(_ time.Time) error  { return nil }
+func (writerConn) SetWriteDeadline(_ time.Time) error { return nil }
+
+// BenchmarkChecksumming compares calls carrying 64 KiB with and without checksumming.
+// net.Pipe has no I/O cost, so the difference is the cost of CRC32C and the frame copies.
+func BenchmarkChecksumming(b *testing.B) {
+	name := strings.Repeat("x", 64<<10)
+	for _, enabled := range []bool{false, true} {
+		b.Run(map[bool]string{false: "off", true: "on"}[enabled], func(b *testing.B) {
+			client, _ := newPipe(b, WithChecksumming(enabled))
+			b.SetBytes(int64(len(name)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				var reply Person
+				if err := client.Call("RPCMethod.SayHello", Person{Name: name}, &reply); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package rpcmux
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is the error of calls rejected by an open circuit breaker.
+var ErrCircuitOpen = errors.New("rpcmux: circuit breaker open")
+
+// breakerWindow is the number of most recent calls whose error rate trips a circuit breaker.
+const breakerWindow = 20
+
+// circuitOpenMethod is the reserved method that requests rejected by a circuit breaker are routed to.
+const circuitOpenMethod = introspectionServiceName + ".CircuitOpen"
+
+// CircuitBreakerPolicy configures a circuit breaker set with WithCircuitBreaker.
+type CircuitBreakerPolicy struct {
+	// ErrorThreshold is the fraction, between 0 and 1, of the last 20 calls that must have
+	// failed for the circuit to open.
+	ErrorThreshold float64
+	// ResetTimeout is how long the circuit stays open before it half-opens.
+	ResetTimeout time.Duration
+	// HalfOpenCalls is how many calls are let through while half-open. The circuit closes
+	// if they all succeed and opens again as soon as one fails.
+	HalfOpenCalls int
+}
+
+// WithCircuitBreaker guards the served method with a circuit breaker, so that a failing handler is
+// not called while it is unlikely to recover. While the circuit is open, requests for method are
+// answered with ErrCircuitOpen without calling the handler. RPCDuplex.Call returns that error
+// itself; calls made with the embedded rpc.Client get a rpc.ServerError with the same text.
+//
+// Duplexes created with the same Option, such as those accepted by a DuplexListener, share the
+// circuit, so that the failures of the handler seen by all of them open it.
+func WithCircuitBreaker(method string, policy CircuitBreakerPolicy) Option {
+	if policy.HalfOpenCalls < 1 {
+		policy.HalfOpenCalls = 1
+	}
+	b := &circuitBreaker{policy: policy}
+	return func(d *RPCDuplex) {
+		if d.breakers == nil {
+			d.breakers = make(map[string]*circuitBreaker)
+		}
+		d.breakers[method] = b
+	}
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker tracks the outcome of the calls to one method.
+type circuitBreaker struct {
+	policy CircuitBreakerPolicy
+
+	mu       sync.Mutex
+	state    breakerState
+	outcomes [breakerWindow]bool // ring of the last calls, true for failures
+	next     int                 // next index in outcomes
+	recorded int                 // number of outcomes recorded, up to breakerWindow
+	failures int                 // failures in outcomes
+	openedAt time.Time
+	admitted int // calls let through while half-open
+	passed   int // successful calls while half-open
+}
+
+// allow reports whether a call may go through, half-opening the circuit once ResetTimeout has passed.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerOpen && time.Since(b.openedAt) >= b.policy.ResetTimeout {
+		b.state, b.admitted, b.passed = breakerHalfOpen, 0, 0
+	}
+	switch b.state {
+	case breakerOpen:
+		return false
+	case breakerHalfOpen:
+		if b.admitted >= b.policy.HalfOpenCalls {
+			return false
+		}
+		b.admitted++
+	}
+	return true
+}
+
+// record records the outcome of a call let through by allow.
+func (b *circuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerHalfOpen:
+		if failed {
+			b.open()
+			return
+		}
+		if b.passed++; b.passed >= b.policy.HalfOpenCalls {
+			b.state = breakerClosed
+			b.outcomes, b.next, b.recorded, b.failures = [breakerWindow]bool{}, 0, 0, 0
+		}
+	case breakerClosed:
+		if b.recorded == breakerWindow && b.outcomes[b.next] {
+			b.failures--
+		}
+		b.outcomes[b.next] = failed
+		b.next = (b.next + 1) % breakerWindow
+		if b.recorded < breakerWindow {
+			b.recorded++
+		}
+		if failed {
+			b.failures++
+		}
+		if b.recorded == breakerWindow && float64(b.failures)/breakerWindow > b.policy.ErrorThreshold {
+			b.open()
+		}
+	}
+}
+
+func (b *circuitBreaker) open() {
+	b.state = breakerOpen
+	b.openedAt = time.Now()
+}
+
+// CircuitOpen answers the requests rejected by a circuit breaker.
+func (s *introspection) CircuitOpen(_ struct{}, _ *struct{}) error {
+	return ErrCircuitOpen
+}
@@ -0,0 +1,100 @@
+package rpcmux
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	policy := CircuitBreakerPolicy{ErrorThreshold: 0.5, ResetTimeout: time.Hour}
+	a, b := newPipe(t, WithCircuitBreaker("Failer.Fail", policy))
+	if err := b.Register(Failer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var reply Person
+	for i := 0; i < breakerWindow; i++ {
+		if err := a.Call("Failer.Fail", Person{}, &reply); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d = %v, want the handler's error", i, err)
+		}
+	}
+	if err := a.Call("Failer.Fail", Person{}, &reply); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Call once the circuit is open = %v, want ErrCircuitOpen", err)
+	}
+	if err := a.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply); err != nil {
+		t.Errorf("Call of a method without breaker: %v", err)
+	}
+}
+
+func TestCircuitBreakerShared(t *testing.T) {
+	opt := WithCircuitBreaker("Failer.Fail", CircuitBreakerPolicy{ErrorThreshold: 0.5, ResetTimeout: time.Hour})
+	a1, b1 := newPipe(t, opt)
+	a2, b2 := newPipe(t, opt)
+	for _, d := range []*RPCDuplex{b1, b2} {
+		if err := d.Register(Failer{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var reply Person
+	for i := 0; i < breakerWindow; i++ {
+		a1.Call("Failer.Fail", Person{}, &reply)
+	}
+	if err := a2.Call("Failer.Fail", Person{}, &reply); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Call through another duplex with the same option = %v, want ErrCircuitOpen", err)
+	}
+}
+
+// trip opens b by recording failures, failing t if a window of them does not open it.
+func trip(t *testing.T, b *circuitBreaker) {
+	t.Helper()
+	for i := 0; i < breakerWindow; i++ {
+		if !b.allow() {
+			return
+		}
+		b.record(true)
+	}
+	if b.allow() {
+		t.Fatal("circuit still closed after a window of failures")
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	const resetTimeout = 20 * time.Millisecond
+	b := &circuitBreaker{policy: CircuitBreakerPolicy{ErrorThreshold: 0.5, ResetTimeout: resetTimeout, HalfOpenCalls: 2}}
+	trip(t, b)
+
+	time.Sleep(resetTimeout)
+	if !b.allow() || !b.allow() {
+		t.Fatal("calls rejected once ResetTimeout passed")
+	}
+	if b.allow() {
+		t.Error("half-open circuit let more than HalfOpenCalls calls through")
+	}
+	b.record(false)
+	if b.allow() {
+		t.Error("circuit closed before all the half-open calls succeeded")
+	}
+	b.record(false)
+	for i := 0; i < breakerWindow; i++ {
+		if !b.allow() {
+			t.Fatalf("call %d rejected after the half-open calls succeeded", i)
+		}
+		b.record(false)
+	}
+
+	trip(t, b)
+	time.Sleep(resetTimeout)
+	if !b.allow() {
+		t.Fatal("call rejected once ResetTimeout passed")
+	}
+	b.record(true)
+	if b.allow() {
+		t.Error("circuit not open again after a half-open call failed")
+	}
+	time.Sleep(resetTimeout)
+	if !b.allow() {
+		t.Error("reopened circuit did not half-open after ResetTimeout")
+	}
+}
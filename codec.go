@@ -0,0 +1,188 @@
+package rpcmux
+
+import (
+	"bufio"
+	"encoding/gob"
+	"io"
+	"net/rpc"
+)
+
+// ServerCodecAdapter wraps a RPCDuplex so that it satisfies rpc.ServerCodec.
+// It speaks the same gob encoding as net/rpc, so existing net/rpc services can be
+// served over the duplex with rpc.ServeCodec without changing their handler code.
+//
+// As required by rpc.ServerCodec, requests are read by a single goroutine and
+// responses written by one goroutine at a time; the adapter only locks the request
+// arguments it keeps for WithFallback. Only one ServerCodecAdapter may read from a
+// duplex at a time: Serve uses one.
+type ServerCodecAdapter struct {
+	d        *RPCDuplex
+	dec      *gob.Decoder
+	enc      *gob.Encoder
+	encBuf   *bufio.Writer
+	closed   bool
+	rejected string      // reserved method the request being read was routed to, "" if accepted
+	header   rpc.Request // header of the request being read
+	pending  pendingArgs // arguments of the requests with a fallback
+}
+
+// NewServerCodecAdapter returns a ServerCodecAdapter reading requests from and writing responses to d.
+func NewServerCodecAdapter(d *RPCDuplex) *ServerCodecAdapter {
+	s := d.mux.serverStream()
+	buf := bufio.NewWriter(s)
+	return &ServerCodecAdapter{
+		d:      d,
+		dec:    gob.NewDecoder(s),
+		enc:    gob.NewEncoder(buf),
+		encBuf: buf,
+	}
+}
+
+// ReadRequestHeader reads the next request header from the duplex.
+// It returns io.EOF, ending rpc.ServeCodec, once the duplex is shutting down.
+// Requests refused by the bulkhead of the duplex or by the circuit breaker of their method
+// are routed to a reserved method answering with ErrBulkheadFull or ErrCircuitOpen.
+func (c *ServerCodecAdapter) ReadRequestHeader(r *rpc.Request) error {
+	if err := c.dec.Decode(r); err != nil {
+		return err
+	}
+	if !c.d.beginServe() {
+		return io.EOF
+	}
+	c.rejected = ""
+	c.header = *r
+	if c.d.bulkhead != nil && !c.d.bulkhead.acquire(c.d.clientHost()) {
+		c.rejected = bulkheadFullMethod
+	} else if b := c.d.breakers[r.ServiceMethod]; b != nil && !b.allow() {
+		c.rejected = circuitOpenMethod
+	}
+	if c.rejected != "" {
+		r.ServiceMethod = c.rejected
+	}
+	return nil
+}
+
+// ReadRequestBody reads the body of the request whose header was just read.
+// A nil body discards the request body, as does a rejected request, unless its
+// arguments are kept for the fallback of a method whose circuit is open.
+func (c *ServerCodecAdapter) ReadRequestBody(body interface{}) error {
+	fallback := c.d.fallbacks[c.header.ServiceMethod]
+	switch {
+	case c.rejected == circuitOpenMethod && fallback != nil:
+		body = c.d.newArgs(c.header.ServiceMethod)
+	case c.rejected != "":
+		body = nil
+	}
+	if err := c.dec.Decode(body); err != nil {
+		return err
+	}
+	if fallback != nil && c.rejected != bulkheadFullMethod {
+		c.pending.put(c.header.Seq, fallback, body)
+	}
+	return nil
+}
+
+// WriteResponse writes a response header followed by its body and flushes it to the duplex.
+// The error of a method with a fallback, including ErrCircuitOpen, is replaced by the result
+// of the fallback.
+// The duplex is closed if the response cannot be encoded.
+func (c *ServerCodecAdapter) WriteResponse(r *rpc.Response, body interface{}) error {
+	defer c.d.endServe()
+	if c.d.bulkhead != nil && r.ServiceMethod != bulkheadFullMethod {
+		c.d.bulkhead.release(c.d.clientHost())
+	}
+	if b := c.d.breakers[r.ServiceMethod]; b != nil {
+		b.record(r.Error != "")
+	}
+	if p, ok := c.pending.take(r.Seq); ok && r.Error != "" {
+		if reply, err := p.fallback(p.args); err != nil {
+			r.Error = err.Error()
+		} else {
+			r.Error, body = "", reply
+		}
+	}
+	if err := c.enc.Encode(r); err != nil {
+		if c.encBuf.Flush() == nil {
+			c.Close()
+		}
+		return err
+	}
+	if err := c.enc.Encode(body); err != nil {
+		if c.encBuf.Flush() == nil {
+			c.Close()
+		}
+		return err
+	}
+	return c.encBuf.Flush()
+}
+
+// Close closes the underlying connection of the duplex, unless the duplex is shutting down,
+// as Shutdown then closes it once the calls in flight are done, or was hijacked.
+// Closing it more than once has no effect.
+func (c *ServerCodecAdapter) Close() error {
+	if c.closed {
+		// Only call Close once.
+		return nil
+	}
+	c.closed = true
+	if c.d.isDraining() || c.d.mux.hijacked.Load() {
+		return nil
+	}
+	return c.d.Conn.Close()
+}
+
+// ClientCodecAdapter wraps a RPCDuplex so that it satisfies rpc.ClientCodec.
+// It lets callers build a *rpc.Client with rpc.NewClientWithCodec and use the
+// standard (*rpc.Client).Call API over the duplex.
+//
+// The rpc.Client serialises calls to WriteRequest and reads responses from a single
+// goroutine, so the adapter adds no locking of its own. Only one ClientCodecAdapter may
+// read from a duplex at a time: NewRPCDuplex creates the one of the embedded rpc.Client.
+type ClientCodecAdapter struct {
+	d      *RPCDuplex
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+}
+
+// NewClientCodecAdapter returns a ClientCodecAdapter writing requests to and reading responses from d.
+func NewClientCodecAdapter(d *RPCDuplex) *ClientCodecAdapter {
+	s := d.mux.clientStream()
+	buf := bufio.NewWriter(s)
+	return &ClientCodecAdapter{
+		d:      d,
+		dec:    gob.NewDecoder(s),
+		enc:    gob.NewEncoder(buf),
+		encBuf: buf,
+	}
+}
+
+// WriteRequest writes a request header followed by its body and flushes it to the duplex.
+func (c *ClientCodecAdapter) WriteRequest(r *rpc.Request, body interface{}) error {
+	if err := c.enc.Encode(r); err != nil {
+		return err
+	}
+	if err := c.enc.Encode(body); err != nil {
+		return err
+	}
+	return c.encBuf.Flush()
+}
+
+// ReadResponseHeader reads the next response header from the duplex.
+func (c *ClientCodecAdapter) ReadResponseHeader(r *rpc.Response) error {
+	return c.dec.Decode(r)
+}
+
+// ReadResponseBody reads the body of the response whose header was just read.
+// A nil body discards the response body.
+func (c *ClientCodecAdapter) ReadResponseBody(body interface{}) error {
+	return c.dec.Decode(body)
+}
+
+// Close closes the underlying connection of the duplex, unless it was hijacked.
+func (c *ClientCodecAdapter) Close() error {
+	if c.d.mux.hijacked.Load() {
+		return nil
+	}
+	return c.d.Conn.Close()
+}
@@ -0,0 +1,55 @@
+// Package rpcmux implements RPCDuplex, a RPC Duplex connection over a single net.Conn:
+// both ends of the connection have a rpc.Server and a rpc.Client, so either side can
+// expose services and call the services of the other.
+//
+// RPCDuplex builds on net/rpc. Calls and replies are gob-encoded net/rpc messages,
+// each made of a header followed by a body:
+//
+//	request:   +----------------------------+--------------+
+//	           | rpc.Request                | args         |
+//	           | ServiceMethod, Seq         | (gob value)  |
+//	           +----------------------------+--------------+
+//
+//	response:  +----------------------------+--------------+
+//	           | rpc.Response               | reply        |
+//	           | ServiceMethod, Seq, Error  | (gob value)  |
+//	           +----------------------------+--------------+
+//
+// Seq ties a response to the request it answers, so calls may be pipelined and
+// their replies may arrive in any order.
+//
+// Messages are written in frames tagged with their direction, 'Q' for requests and
+// 'R' for responses. Messages longer than 1 MiB are split over several frames. Each end
+// starts with an 'H' frame whose payload is its ProtocolVersion, as 2 bytes big-endian;
+// a peer speaking another version is refused with ErrVersionMismatch:
+//
+//	frame:     +-----+---------------+-------------------+
+//	           | tag | length        | payload           |
+//	           | 1B  | 4B big-endian | length bytes      |
+//	           +-----+---------------+-------------------+
+//
+// A single goroutine per end reads the connection, handing requests to the rpc.Server
+// and responses to the rpc.Client, and writers share one lock so that frames are never
+// interleaved. Both directions can therefore be used at the same time. A peer that starts
+// a frame and stalls can be cut off with WithFrameHeaderTimeout.
+//
+// # Roles
+//
+// There is no initiator or responder: both ends are created the same way with
+// NewRPCDuplex, and neither waits for the hello frame of the other before calling. An end acts as a server by registering services with
+// Register and running Serve, and as a client by using Call, Batch or HealthCheck.
+//
+// # Examples
+//
+// example/hello is the worked example. It connects two RPCDuplex over net.Pipe, registers
+// RPCMethod on one end, serves it, and calls RPCMethod.SayHello from the other end.
+// Running it prints the name sent in the call:
+//
+//	go run github.com/atang152/test_duplex/example/hello
+//
+// # Limitations
+//
+//   - A context passed to a call only stops the caller from waiting. The request is
+//     still delivered, and the remote handler still runs to completion.
+//   - There are no streaming calls.
+package rpcmux
@@ -0,0 +1,209 @@
+package rpcmux
+
+import (
+	"context"
+	"net"
+	"net/rpc"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// RPCDuplex represents a RPC Duplex implementation where both ends of the connection
+// has a rpc.Server and a rpc.Client.
+//
+// The embedded net.Conn, rpc.Client and rpc.Server stay accessible, so every method
+// of those types can be used on a RPCDuplex directly. Call, Register and Close are
+// defined on RPCDuplex itself so that calls are counted and traced, and shutdown is
+// coordinated. A RPCDuplex is safe for concurrent use by multiple goroutines.
+//
+// The connection carries framed messages (see the package documentation), so the embedded net.Conn must not
+// be read from or written to directly once the duplex is created.
+type RPCDuplex struct {
+	net.Conn
+	*rpc.Client
+	*rpc.Server
+
+	err       error // first error reported by an Option
+	trace     *callTrace
+	stats     *methodStats
+	proxyAddr net.Addr // client address read by WithPROXYProtocolV2
+	mux       *demux   // reads the connection for the rpc.Client and the rpc.Server
+
+	frameTimeout time.Duration             // set by WithFrameHeaderTimeout
+	wrappers     []func(net.Conn) net.Conn // set by wrap, applied once every option ran
+
+	mu       sync.Mutex
+	draining bool                    // set by Shutdown, refuses new calls
+	inflight sync.WaitGroup          // calls that have not completed yet
+	serving  sync.WaitGroup          // requests read by the server and not answered yet
+	health   *healthService          // created by the first ServeHealthCheck or SetHealthStatus
+	methods  map[string]MethodInfo   // methods published by Register and RegisterName
+	argTypes map[string]reflect.Type // argument types of methods, without the pointer
+	schemas  map[string]MethodSchema // schemas published by RegisterSchema
+	chans    map[string]*chanQueue   // values received for each RemoteChan
+
+	breakers  map[string]*circuitBreaker // set by WithCircuitBreaker, read-only afterwards
+	bulkhead  *bulkhead                  // set by WithBulkhead
+	fallbacks map[string]fallbackFunc    // set by WithFallback, read-only afterwards
+}
+
+// Option configures optional behaviour of a RPCDuplex when it is created.
+// Options are applied by NewRPCDuplex in order, before the rpc.Client starts reading from the connection.
+type Option func(*RPCDuplex)
+
+// RPCMethod is a receiver which we will use Register to publishes the receiver's methods in the DefaultServer.
+// It is the service exposed by example/hello.
+type RPCMethod struct{}
+
+// Person is a struct that A will use to expose it's RPC method.
+// It is both the argument and the reply of RPCMethod.SayHello.
+type Person struct {
+	Name string
+}
+
+// SayHello is a RPC method that replies with the person it was given.
+// RPC methods must look schematically like: func (t *T) MethodName(argType T1, replyType *T2) error
+func (RPCMethod) SayHello(person Person, reply *Person) error {
+	*reply = person
+	return nil
+}
+
+// NewRPCDuplex takes in a single net.Conn and returns a RPC Duplex construct.
+// A goroutine starts reading conn straight away, handing responses to the rpc.Client
+// and queueing requests for the rpc.Server, which does not handle them until Serve is
+// called. The RPCDuplex owns conn from here on:
+// it is closed by Close or Shutdown.
+//
+// If an option fails, for example because a PROXY protocol header cannot be read,
+// conn is closed and every call on the returned RPCDuplex fails. Constructors that
+// return an error, such as DuplexListener.Accept, report the failure instead.
+func NewRPCDuplex(conn net.Conn, opts ...Option) *RPCDuplex {
+	d, _ := newRPCDuplex(conn, opts...)
+	return d
+}
+
+// newRPCDuplex is NewRPCDuplex, also returning the first error reported by the options.
+func newRPCDuplex(conn net.Conn, opts ...Option) (*RPCDuplex, error) {
+	d := &RPCDuplex{Conn: conn, Server: rpc.NewServer(), stats: newMethodStats()}
+	d.Server.RegisterName(introspectionServiceName, &introspection{d: d})
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.applyWrappers()
+	d.mux = newDemux(d.Conn, d.frameTimeout)
+	go d.mux.run()
+	d.mux.sendHello()
+	d.Client = rpc.NewClientWithCodec(NewClientCodecAdapter(d))
+	if d.err != nil {
+		d.Close()
+		return d, d.err
+	}
+	return d, nil
+}
+
+// wrap makes an option wrap the connection once all the options ran, so that the options
+// reading or writing it directly, such as those of the PROXY protocol, use the bare connection.
+// Wrappers are applied in the order their options were given.
+func (d *RPCDuplex) wrap(wrapper func(net.Conn) net.Conn) {
+	d.wrappers = append(d.wrappers, wrapper)
+}
+
+// applyWrappers wraps the connection with the wrappers recorded by wrap.
+func (d *RPCDuplex) applyWrappers() {
+	for _, wrap := range d.wrappers {
+		d.Conn = wrap(d.Conn)
+	}
+	d.wrappers = nil
+}
+
+// fail records an error from an option. Only the first error is kept.
+func (d *RPCDuplex) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+// Call invokes the named function on the remote end, waits for it to complete, and returns its error status.
+// Errors returned by the remote handler are of type rpc.ServerError, except ErrCircuitOpen
+// and ErrBulkheadFull, which are returned as is. Call may be used by many goroutines at once;
+// the calls are pipelined over the connection.
+func (d *RPCDuplex) Call(serviceMethod string, args interface{}, reply interface{}) error {
+	return d.call(context.Background(), serviceMethod, args, reply)
+}
+
+// CallContext is like Call, but stops waiting and returns ctx.Err() if ctx is done before
+// the reply arrives. The request is not withdrawn: the remote handler still runs.
+func (d *RPCDuplex) CallContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	return d.call(ctx, serviceMethod, args, reply)
+}
+
+// call is Call, returning ctx.Err() early if ctx is done before the reply arrives.
+func (d *RPCDuplex) call(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	if err := d.beginCall(); err != nil {
+		return err
+	}
+	defer d.endCall()
+
+	start := time.Now()
+	var err error
+	select {
+	case c := <-d.Go(serviceMethod, args, reply, make(chan *rpc.Call, 1)).Done:
+		err = d.callError(c.Error)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	d.record(serviceMethod, start, err)
+	return err
+}
+
+// record counts a call started at start in the method stats and the call trace.
+func (d *RPCDuplex) record(serviceMethod string, start time.Time, err error) {
+	elapsed := time.Since(start)
+	d.stats.record(serviceMethod, elapsed, err)
+	if d.trace != nil {
+		d.trace.record(CallTraceEntry{
+			Time:    start,
+			Method:  serviceMethod,
+			Elapsed: elapsed,
+			Errored: err != nil,
+		})
+	}
+}
+
+// callError returns the error of a call as its caller sees it: the rpc.ServerError answered
+// by the peer for a rejected request is turned back into the error it was made from, and
+// rpc.ErrShutdown into the error that made the duplex close the connection, if any, so that
+// callers can recognise them with errors.Is.
+func (d *RPCDuplex) callError(err error) error {
+	switch err {
+	case rpc.ErrShutdown:
+		if ferr := d.mux.failure(); ferr != nil {
+			return ferr
+		}
+	case rpc.ServerError(ErrCircuitOpen.Error()):
+		return ErrCircuitOpen
+	case rpc.ServerError(ErrBulkheadFull.Error()):
+		return ErrBulkheadFull
+	}
+	return err
+}
+
+// Register registers rcvr in the server, making it visible as a service with the name of the type of rcvr.
+// It returns an error, as rpc.Server.Register does, if rcvr has no suitable methods.
+// Services should be registered before Serve is called.
+func (d *RPCDuplex) Register(rcvr interface{}) error {
+	if err := d.Server.Register(rcvr); err != nil {
+		return err
+	}
+	d.recordMethods(reflect.Indirect(reflect.ValueOf(rcvr)).Type().Name(), rcvr)
+	return nil
+}
+
+// Serve serves the rpc.Server via net.Conn.
+// It blocks until the connection is closed, so it is usually run in its own goroutine,
+// and then returns nil: errors reading requests end the connection rather than being reported.
+func (d *RPCDuplex) Serve() error {
+	d.Server.ServeCodec(NewServerCodecAdapter(d))
+	return nil
+}
@@ -0,0 +1,322 @@
+package rpcmux
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newPipe returns two duplexes connected over net.Pipe, both serving RPCMethod.
+func newPipe(t testing.TB, opts ...Option) (a, b *RPCDuplex) {
+	t.Helper()
+	connA, connB := net.Pipe()
+	return serveBoth(t, connA, connB, opts...)
+}
+
+// serveBoth creates a duplex on each conn with opts and serves RPCMethod on both.
+func serveBoth(t testing.TB, connA, connB net.Conn, opts ...Option) (a, b *RPCDuplex) {
+	t.Helper()
+	a = NewRPCDuplex(connA, opts...)
+	b = NewRPCDuplex(connB, opts...)
+	for _, d := range []*RPCDuplex{a, b} {
+		d := d
+		if err := d.Register(new(RPCMethod)); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+		go d.Serve()
+		t.Cleanup(func() { d.Close() })
+	}
+	return a, b
+}
+
+// serveAccepted serves RPCMethod on every duplex accepted from dl, until t ends.
+func serveAccepted(t *testing.T, dl *DuplexListener) {
+	var mu sync.Mutex
+	var accepted []*RPCDuplex
+	t.Cleanup(func() {
+		dl.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, d := range accepted {
+			d.Close()
+		}
+	})
+	go func() {
+		for {
+			d, err := dl.Accept()
+			if err != nil {
+				return
+			}
+			d.Register(new(RPCMethod))
+			go d.Serve()
+			mu.Lock()
+			accepted = append(accepted, d)
+			mu.Unlock()
+		}
+	}()
+}
+
+// callBothWays makes n concurrent SayHello calls in each direction and fails t on any error
+// or wrong reply.
+func callBothWays(t *testing.T, a, b *RPCDuplex, n int, name string) {
+	t.Helper()
+	var wg sync.WaitGroup
+	errs := make(chan string, 2*n)
+	for i := 0; i < n; i++ {
+		for _, d := range []*RPCDuplex{a, b} {
+			wg.Add(1)
+			go func(d *RPCDuplex) {
+				defer wg.Done()
+				var reply Person
+				if err := d.Call("RPCMethod.SayHello", Person{Name: name}, &reply); err != nil {
+					errs <- err.Error()
+				} else if reply.Name != name {
+					errs <- "wrong reply"
+				}
+			}(d)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("calls did not complete")
+	}
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestACallsB(t *testing.T) {
+	a, _ := newPipe(t)
+
+	var reply Person
+	if err := a.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if reply.Name != "Anto" {
+		t.Errorf("reply = %q, want %q", reply.Name, "Anto")
+	}
+}
+
+func TestCallsBothWays(t *testing.T) {
+	a, b := newPipe(t)
+	callBothWays(t, a, b, 50, "Anto")
+}
+
+// TestMassConcurrentCalls makes 1000 round trips at once, half in each direction, and checks
+// that no goroutine outlives the duplexes. Run it with -race.
+func TestMassConcurrentCalls(t *testing.T) {
+	before := runtime.NumGoroutine()
+	a, b := newPipe(t)
+	callBothWays(t, a, b, 500, "Anto")
+	a.Close()
+	b.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left after closing the duplexes, want at most %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCallsBothWaysTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			close(accepted)
+			return
+		}
+		accepted <- conn
+	}()
+	connA, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	connB, ok := <-accepted
+	if !ok {
+		t.Fatal("Accept failed")
+	}
+
+	a, b := serveBoth(t, connA, connB)
+	callBothWays(t, a, b, 20, "Anto")
+}
+
+// TestLargeMessagesBothWays sends messages spanning several frames in both directions at
+// once, so that requests and responses written by the same end are interleaved.
+func TestLargeMessagesBothWays(t *testing.T) {
+	a, b := newPipe(t)
+	callBothWays(t, a, b, 4, strings.Repeat("x", 3*maxFramePayload+17))
+}
+
+func TestMalformedFrameClosesDuplex(t *testing.T) {
+	connA, connB := net.Pipe()
+	d := NewRPCDuplex(connA)
+	defer d.Close()
+
+	go connB.Write(append(helloFrame(), 'X', 0, 0, 0, 1, 0))
+
+	var reply Person
+	if err := d.Call("RPCMethod.SayHello", Person{}, &reply); !errors.Is(err, ErrBadFrame) {
+		t.Fatalf("Call over a malformed stream = %v, want ErrBadFrame", err)
+	}
+}
+
+// TestDroppedConnectionUnblocksCall fails the reads of the connection while a call waits for
+// its reply, which must then return instead of blocking forever.
+func TestDroppedConnectionUnblocksCall(t *testing.T) {
+	connA, connB := net.Pipe()
+	a, b := NewRPCDuplex(connA), NewRPCDuplex(connB) // b never serves, so the call stays pending
+	defer a.Close()
+	defer b.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		var reply Person
+		errc <- a.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply)
+	}()
+	time.Sleep(10 * time.Millisecond) // let the request reach b
+	a.SetReadDeadline(time.Now())
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("Call = %v, want os.ErrDeadlineExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Call still blocked after the connection failed")
+	}
+}
+
+// Failer is a service whose calls always fail.
+type Failer struct{}
+
+func (Failer) Fail(_ Person, _ *Person) error {
+	return errors.New("boom")
+}
+
+func TestCallServerError(t *testing.T) {
+	a, b := newPipe(t)
+	if err := b.Register(Failer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var reply Person
+	err := a.Call("Failer.Fail", Person{Name: "Anto"}, &reply)
+	if _, ok := err.(rpc.ServerError); !ok || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Call = %#v, want a rpc.ServerError containing %q", err, "boom")
+	}
+}
+
+// maxAllocsPerCall bounds the allocations of one call made and served over net.Pipe.
+// testing.AllocsPerRun counts those of both ends; a SayHello call makes 20 of them.
+const maxAllocsPerCall = 25
+
+func TestAllocsPerRun(t *testing.T) {
+	a, _ := newPipe(t)
+
+	var reply Person
+	allocs := testing.AllocsPerRun(1000, func() {
+		if err := a.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > maxAllocsPerCall {
+		t.Errorf("%.1f allocations per call, want at most %d", allocs, maxAllocsPerCall)
+	}
+}
+
+// Delayed is a service whose methods reply after 100ms with a reply of their own.
+type Delayed struct{}
+
+func (Delayed) Method1(_ Person, reply *Person) error {
+	time.Sleep(100 * time.Millisecond)
+	reply.Name = "method1"
+	return nil
+}
+
+func (Delayed) Method2(_ Person, reply *Person) error {
+	time.Sleep(100 * time.Millisecond)
+	reply.Name = "method2"
+	return nil
+}
+
+// TestConcurrentChannels makes two delayed calls at once: each must get its own reply, and
+// neither must wait for the other.
+func TestConcurrentChannels(t *testing.T) {
+	a, b := newPipe(t)
+	if err := b.Register(Delayed{}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for method, want := range map[string]string{"Delayed.Method1": "method1", "Delayed.Method2": "method2"} {
+		wg.Add(1)
+		go func(method, want string) {
+			defer wg.Done()
+			var reply Person
+			if err := a.Call(method, Person{}, &reply); err != nil {
+				t.Errorf("Call of %s: %v", method, err)
+			} else if reply.Name != want {
+				t.Errorf("Call of %s replied %q, want %q", method, reply.Name, want)
+			}
+		}(method, want)
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed >= 200*time.Millisecond {
+		t.Errorf("calls took %v, want them to run at the same time", elapsed)
+	}
+}
+
+// TestCloseUnblocksPendingReads closes a duplex while calls wait for replies from a peer
+// that never serves: they must all fail promptly.
+func TestCloseUnblocksPendingReads(t *testing.T) {
+	connA, connB := net.Pipe()
+	a, b := NewRPCDuplex(connA), NewRPCDuplex(connB)
+	defer b.Close()
+
+	const n = 10
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			var reply Person
+			errs <- a.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond) // let the calls be sent
+	a.Close()
+
+	timeout := time.After(100 * time.Millisecond)
+	for i := 0; i < n; i++ {
+		select {
+		case err := <-errs:
+			if err == nil {
+				t.Error("Call succeeded on a closed duplex")
+			}
+		case <-timeout:
+			t.Fatalf("%d calls still blocked 100ms after Close", n-i)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Package election elects a leader among peers connected to a common coordinator over RPCDuplex.
+//
+// This is not a distributed algorithm such as Bully or Raft: a single central Coordinator
+// decides, and the peers only learn its decision. The coordinator end serves a Coordinator
+// with Serve on the duplex of every peer. Each peer campaigns with Elect, renewing its
+// candidacy well within its TTL. The live candidate with the highest ID is the leader; a
+// candidate that stops renewing is dropped once its TTL expires, and the next highest takes
+// over. While the Coordinator is down or unreachable there is no leader.
+package election
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	rpcmux "github.com/atang152/test_duplex"
+)
+
+// serviceName is the name the Coordinator is registered under.
+const serviceName = "Election"
+
+// MinTTL is the shortest TTL accepted by Elect. Candidacies are renewed every TTL/3, so it
+// bounds the rate at which each peer calls the Coordinator to one call every 10ms.
+const MinTTL = 30 * time.Millisecond
+
+// ErrBadTTL is returned by Elect for a TTL shorter than MinTTL.
+var ErrBadTTL = errors.New("election: TTL too short")
+
+// CampaignRequest is the argument of Election.Campaign.
+type CampaignRequest struct {
+	ID  string
+	TTL time.Duration
+}
+
+// CampaignReply is the reply of Election.Campaign.
+type CampaignReply struct {
+	Leader string
+}
+
+// Coordinator keeps track of the candidates and decides who leads.
+type Coordinator struct {
+	mu         sync.Mutex
+	candidates map[string]time.Time // candidate ID to the expiry of its candidacy
+}
+
+// NewCoordinator returns a Coordinator with no candidates.
+func NewCoordinator() *Coordinator {
+	return &Coordinator{candidates: make(map[string]time.Time)}
+}
+
+// Serve registers c on d so that the peer at the other end can campaign.
+// The same Coordinator is served on the duplex of every peer taking part.
+func (c *Coordinator) Serve(d *rpcmux.RPCDuplex) error {
+	return d.RegisterName(serviceName, &coordinatorService{c})
+}
+
+// Leader returns the ID of the current leader, or "" if there are no live candidates.
+func (c *Coordinator) Leader() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.leaderLocked(time.Now())
+}
+
+func (c *Coordinator) leaderLocked(now time.Time) string {
+	leader := ""
+	for id, expiry := range c.candidates {
+		if now.After(expiry) {
+			delete(c.candidates, id)
+			continue
+		}
+		if id > leader {
+			leader = id
+		}
+	}
+	return leader
+}
+
+// coordinatorService is the RPC facade of a Coordinator.
+type coordinatorService struct {
+	c *Coordinator
+}
+
+// Campaign renews the candidacy of req.ID for req.TTL and replies with the current leader.
+func (s *coordinatorService) Campaign(req CampaignRequest, reply *CampaignReply) error {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+
+	now := time.Now()
+	s.c.candidates[req.ID] = now.Add(req.TTL)
+	reply.Leader = s.c.leaderLocked(now)
+	return nil
+}
+
+// Resign withdraws the candidacy of id and replies with the new leader.
+func (s *coordinatorService) Resign(id string, reply *CampaignReply) error {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+
+	delete(s.c.candidates, id)
+	reply.Leader = s.c.leaderLocked(time.Now())
+	return nil
+}
+
+// Election is the candidacy of one peer.
+type Election struct {
+	d   *rpcmux.RPCDuplex
+	id  string
+	ttl time.Duration
+
+	mu      sync.Mutex
+	leader  string
+	changed chan string
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Elect puts id forward as a candidate with the Coordinator served at the other end of d,
+// and keeps renewing the candidacy every ttl/3 until Resign is called.
+// It returns once the first campaign has completed. The TTL must be at least MinTTL.
+func Elect(d *rpcmux.RPCDuplex, id string, ttl time.Duration) (*Election, error) {
+	if ttl < MinTTL {
+		return nil, ErrBadTTL
+	}
+	e := &Election{
+		d:       d,
+		id:      id,
+		ttl:     ttl,
+		changed: make(chan string, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := e.campaign(); err != nil {
+		return nil, err
+	}
+	go e.run()
+	return e, nil
+}
+
+// IsLeader reports whether this candidate was the leader at the last campaign.
+func (e *Election) IsLeader() bool {
+	return e.Leader() == e.id
+}
+
+// Leader returns the leader seen at the last campaign, or "" if the coordinator could not be reached.
+func (e *Election) Leader() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// LeaderChanged delivers the ID of the leader whenever it changes.
+// Only the latest change is kept if the receiver falls behind.
+func (e *Election) LeaderChanged() <-chan string {
+	return e.changed
+}
+
+// Resign stops campaigning and withdraws the candidacy from the coordinator.
+// It must be called only once.
+func (e *Election) Resign() error {
+	close(e.stop)
+	<-e.done
+
+	var reply CampaignReply
+	err := e.d.Call(serviceName+".Resign", e.id, &reply)
+	e.setLeader("")
+	return err
+}
+
+func (e *Election) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if e.campaign() != nil {
+				// The coordinator is unreachable: nobody can be known to lead.
+				e.setLeader("")
+			}
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+func (e *Election) campaign() error {
+	var reply CampaignReply
+	if err := e.d.Call(serviceName+".Campaign", CampaignRequest{ID: e.id, TTL: e.ttl}, &reply); err != nil {
+		return err
+	}
+	e.setLeader(reply.Leader)
+	return nil
+}
+
+func (e *Election) setLeader(leader string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if leader == e.leader {
+		return
+	}
+	e.leader = leader
+	select {
+	case <-e.changed:
+	default:
+	}
+	e.changed <- leader
+}
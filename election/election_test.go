@@ -0,0 +1,66 @@
+package election
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atang152/test_duplex/internal/rpctest"
+)
+
+func TestElect(t *testing.T) {
+	c := NewCoordinator()
+	a, err := Elect(rpctest.Serve(t, c.Serve), "a", time.Second)
+	if err != nil {
+		t.Fatalf("Elect: %v", err)
+	}
+	b, err := Elect(rpctest.Serve(t, c.Serve), "b", time.Second)
+	if err != nil {
+		t.Fatalf("Elect: %v", err)
+	}
+
+	if !b.IsLeader() || c.Leader() != "b" {
+		t.Errorf("leader = %q, want %q", c.Leader(), "b")
+	}
+	if err := b.Resign(); err != nil {
+		t.Fatalf("Resign: %v", err)
+	}
+	if c.Leader() != "a" {
+		t.Errorf("leader after Resign = %q, want %q", c.Leader(), "a")
+	}
+
+	// a learns it leads at its next campaign, within a third of its TTL.
+	deadline := time.Now().Add(2 * time.Second)
+	for !a.IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatalf("a sees %q as the leader, want itself", a.Leader())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := a.Resign(); err != nil {
+		t.Errorf("Resign: %v", err)
+	}
+}
+
+func TestElectBadTTL(t *testing.T) {
+	c := NewCoordinator()
+	for _, ttl := range []time.Duration{0, -time.Second, time.Nanosecond, MinTTL - 1} {
+		if _, err := Elect(rpctest.Serve(t, c.Serve), "a", ttl); err != ErrBadTTL {
+			t.Errorf("Elect with TTL %v = %v, want ErrBadTTL", ttl, err)
+		}
+	}
+}
+
+func TestElectMinTTL(t *testing.T) {
+	e, err := Elect(rpctest.Serve(t, NewCoordinator().Serve), "a", MinTTL)
+	if err != nil {
+		t.Fatalf("Elect with MinTTL: %v", err)
+	}
+	// The candidacy is renewed in time to keep the lead.
+	time.Sleep(3 * MinTTL)
+	if !e.IsLeader() {
+		t.Errorf("leader = %q, want %q", e.Leader(), "a")
+	}
+	if err := e.Resign(); err != nil {
+		t.Errorf("Resign: %v", err)
+	}
+}
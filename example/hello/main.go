@@ -0,0 +1,42 @@
+// Hello connects two RPCDuplex over net.Pipe, serves RPCMethod on one end
+// and calls RPCMethod.SayHello from the other.
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+
+	rpcmux "github.com/atang152/test_duplex"
+)
+
+func main() {
+
+	object := new(rpcmux.RPCMethod)
+
+	connA, connB := net.Pipe()
+	defer connA.Close()
+	defer connB.Close()
+
+	go func() {
+		svr := rpcmux.NewRPCDuplex(connA)
+		if err := svr.Register(object); err != nil {
+			log.Fatal("register: ", err)
+		}
+		svr.Serve()
+	}()
+
+	// Client
+	var reply rpcmux.Person
+	testInput := rpcmux.Person{Name: "Anto"}
+
+	clientA := rpcmux.NewRPCDuplex(connB)
+	err := clientA.Call("RPCMethod.SayHello", testInput, &reply)
+
+	if err != nil {
+		log.Fatal("error", err)
+	}
+
+	fmt.Println(reply.Name)
+
+}
@@ -0,0 +1,29 @@
+package rpcmux_test
+
+import (
+	"fmt"
+	"log"
+	"net"
+
+	rpcmux "github.com/atang152/test_duplex"
+)
+
+func ExampleNewRPCDuplex() {
+	connA, connB := net.Pipe()
+
+	server := rpcmux.NewRPCDuplex(connA)
+	defer server.Close()
+	if err := server.Register(new(rpcmux.RPCMethod)); err != nil {
+		log.Fatal("register: ", err)
+	}
+	go server.Serve()
+
+	client := rpcmux.NewRPCDuplex(connB)
+	defer client.Close()
+	var reply rpcmux.Person
+	if err := client.Call("RPCMethod.SayHello", rpcmux.Person{Name: "Anto"}, &reply); err != nil {
+		log.Fatal("call: ", err)
+	}
+	fmt.Println(reply.Name)
+	// Output: Anto
+}
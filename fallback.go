@@ -0,0 +1,60 @@
+package rpcmux
+
+import (
+	"reflect"
+	"sync"
+)
+
+// WithFallback answers the requests for the served method whose handler returns an error with
+// the result of fallback instead, so that degraded responses can be served without changing
+// the handler. Requests rejected by the circuit breaker of the method are answered by fallback
+// too; those rejected by WithBulkhead are not. fallback is called with the request argument as
+// a value, even if the handler takes a pointer, and its result must encode like the handler's
+// reply. If fallback fails too, the call fails with the error of fallback.
+func WithFallback(method string, fallback func(args interface{}) (interface{}, error)) Option {
+	return func(d *RPCDuplex) {
+		if d.fallbacks == nil {
+			d.fallbacks = make(map[string]fallbackFunc)
+		}
+		d.fallbacks[method] = fallback
+	}
+}
+
+type fallbackFunc func(args interface{}) (interface{}, error)
+
+// pendingArgs keeps the arguments of the requests with a fallback until they are answered.
+// They are added by the goroutine reading requests and taken by those writing responses.
+type pendingArgs struct {
+	mu   sync.Mutex
+	args map[uint64]pendingCall
+}
+
+// pendingCall is a request with a fallback, waiting for its response.
+type pendingCall struct {
+	fallback fallbackFunc
+	args     interface{}
+}
+
+func (p *pendingArgs) put(seq uint64, fallback fallbackFunc, body interface{}) {
+	v := reflect.ValueOf(body)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.args == nil {
+		p.args = make(map[uint64]pendingCall)
+	}
+	p.args[seq] = pendingCall{fallback: fallback, args: v.Elem().Interface()}
+}
+
+func (p *pendingArgs) take(seq uint64) (pendingCall, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	args, ok := p.args[seq]
+	delete(p.args, seq)
+	return args, ok
+}
@@ -0,0 +1,51 @@
+package rpcmux
+
+import (
+	"testing"
+	"time"
+)
+
+// greetFallback answers a failed Failer.Fail with a greeting for the person it was given.
+func greetFallback(args interface{}) (interface{}, error) {
+	return Person{Name: "fallback " + args.(Person).Name}, nil
+}
+
+func TestFallback(t *testing.T) {
+	a, b := newPipe(t, WithFallback("Failer.Fail", greetFallback))
+	if err := b.Register(Failer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var reply Person
+	if err := a.Call("Failer.Fail", Person{Name: "Anto"}, &reply); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if reply.Name != "fallback Anto" {
+		t.Errorf("reply = %q, want %q", reply.Name, "fallback Anto")
+	}
+}
+
+func TestFallbackOfOpenCircuit(t *testing.T) {
+	policy := CircuitBreakerPolicy{ErrorThreshold: 0.5, ResetTimeout: time.Hour}
+	a, b := newPipe(t, WithCircuitBreaker("Failer.Fail", policy), WithFallback("Failer.Fail", greetFallback))
+	if err := b.Register(Failer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every call is answered by the fallback, before and after the circuit opens.
+	for i := 0; i < 2*breakerWindow; i++ {
+		var reply Person
+		if err := a.Call("Failer.Fail", Person{Name: "Anto"}, &reply); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if reply.Name != "fallback Anto" {
+			t.Fatalf("call %d: reply = %q, want %q", i, reply.Name, "fallback Anto")
+		}
+	}
+	breaker := b.breakers["Failer.Fail"]
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	if breaker.state != breakerOpen {
+		t.Error("circuit not open after the handler failed")
+	}
+}
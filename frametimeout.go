@@ -0,0 +1,29 @@
+package rpcmux
+
+import (
+	"errors"
+	"time"
+)
+
+// minFrameThroughput is the slowest rate, in bytes per second, at which a peer created
+// WithFrameHeaderTimeout may send the payload of a frame.
+const minFrameThroughput = 64 << 10
+
+// ErrFrameTimeout is the error of the calls of a duplex created WithFrameHeaderTimeout whose
+// peer did not send a frame in time. The connection is closed when it happens.
+var ErrFrameTimeout = errors.New("rpcmux: frame not received in time")
+
+// WithFrameHeaderTimeout limits the time the peer may take to send a frame once it has started,
+// so that a peer sending part of a frame cannot pin the reading goroutine of the duplex forever.
+// The header must arrive within d of its first byte, and the payload within d plus the time
+// taken to receive it at 64 KiB/s. An idle connection, between frames, is not timed out.
+func WithFrameHeaderTimeout(d time.Duration) Option {
+	return func(rd *RPCDuplex) {
+		rd.frameTimeout = d
+	}
+}
+
+// payloadDeadline returns the deadline for receiving a payload of n bytes from now.
+func payloadDeadline(timeout time.Duration, n uint32) time.Time {
+	return time.Now().Add(timeout + time.Duration(n)*time.Second/minFrameThroughput)
+}
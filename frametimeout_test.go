@@ -0,0 +1,46 @@
+package rpcmux
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestFrameHeaderTimeout(t *testing.T) {
+	connA, connB := net.Pipe()
+	d := NewRPCDuplex(connA, WithFrameHeaderTimeout(50*time.Millisecond))
+	defer d.Close()
+	defer connB.Close()
+
+	// Read the request of the call, and answer with the start of a header only.
+	go func() {
+		buf := make([]byte, 1024)
+		connB.Read(buf)
+		connB.Write([]byte{tagResponse, 0})
+	}()
+
+	errc := make(chan error, 1)
+	go func() {
+		var reply Person
+		errc <- d.Call("RPCMethod.SayHello", Person{}, &reply)
+	}()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrFrameTimeout) {
+			t.Errorf("Call = %v, want ErrFrameTimeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Call still blocked on a partial frame header")
+	}
+}
+
+func TestFrameHeaderTimeoutIdle(t *testing.T) {
+	a, _ := newPipe(t, WithFrameHeaderTimeout(20*time.Millisecond))
+	time.Sleep(100 * time.Millisecond)
+
+	var reply Person
+	if err := a.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply); err != nil {
+		t.Errorf("Call after an idle period: %v", err)
+	}
+}
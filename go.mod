@@ -0,0 +1,9 @@
+module github.com/atang152/test_duplex
+
+go 1.21
+
+require (
+	github.com/Microsoft/go-winio v0.6.2
+	golang.org/x/net v0.35.0
+	golang.org/x/sys v0.30.0
+)
@@ -0,0 +1,101 @@
+package rpcmux
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// healthServiceName is the service name of the gRPC Health Checking Protocol v1.
+const healthServiceName = "grpc.health.v1.Health"
+
+// HealthStatus is the serving status of a service, as defined by grpc.health.v1.
+type HealthStatus int32
+
+// Serving statuses, numbered as in grpc.health.v1.HealthCheckResponse.ServingStatus.
+const (
+	HealthUnknown HealthStatus = iota
+	HealthServing
+	HealthNotServing
+	HealthServiceUnknown
+)
+
+// String returns the name of the status as spelled in grpc.health.v1.
+func (s HealthStatus) String() string {
+	switch s {
+	case HealthUnknown:
+		return "UNKNOWN"
+	case HealthServing:
+		return "SERVING"
+	case HealthNotServing:
+		return "NOT_SERVING"
+	case HealthServiceUnknown:
+		return "SERVICE_UNKNOWN"
+	}
+	return fmt.Sprintf("HealthStatus(%d)", int32(s))
+}
+
+// HealthCheckRequest is the argument of grpc.health.v1.Health.Check.
+// An empty Service asks for the health of the server as a whole.
+type HealthCheckRequest struct {
+	Service string
+}
+
+// HealthCheckResponse is the reply of grpc.health.v1.Health.Check.
+type HealthCheckResponse struct {
+	Status HealthStatus
+}
+
+// ServeHealthCheck registers the grpc.health.v1.Health service so the peer can probe this end of the duplex.
+// The server as a whole reports HealthServing unless changed with SetHealthStatus.
+// Health.Watch is not provided, as the duplex has no streaming calls.
+func (d *RPCDuplex) ServeHealthCheck() error {
+	return d.RegisterName(healthServiceName, d.healthService())
+}
+
+// SetHealthStatus sets the status reported by ServeHealthCheck for service.
+// An empty service sets the status of the server as a whole.
+func (d *RPCDuplex) SetHealthStatus(service string, status HealthStatus) {
+	h := d.healthService()
+	h.mu.Lock()
+	h.statuses[service] = status
+	h.mu.Unlock()
+}
+
+// HealthCheck asks the peer for the health of service using grpc.health.v1.Health.Check.
+func (d *RPCDuplex) HealthCheck(ctx context.Context, service string) (HealthStatus, error) {
+	var resp HealthCheckResponse
+	if err := d.call(ctx, healthServiceName+".Check", HealthCheckRequest{Service: service}, &resp); err != nil {
+		return HealthUnknown, err
+	}
+	return resp.Status, nil
+}
+
+func (d *RPCDuplex) healthService() *healthService {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.health == nil {
+		d.health = &healthService{statuses: map[string]HealthStatus{"": HealthServing}}
+	}
+	return d.health
+}
+
+// healthService implements the grpc.health.v1.Health service.
+type healthService struct {
+	mu       sync.RWMutex
+	statuses map[string]HealthStatus
+}
+
+// Check reports the status of the requested service.
+func (h *healthService) Check(req HealthCheckRequest, resp *HealthCheckResponse) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	status, ok := h.statuses[req.Service]
+	if !ok {
+		return fmt.Errorf("health: unknown service %q", req.Service)
+	}
+	resp.Status = status
+	return nil
+}
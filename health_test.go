@@ -0,0 +1,25 @@
+package rpcmux
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHealthCheck(t *testing.T) {
+	a, b := newPipe(t)
+	if err := b.ServeHealthCheck(); err != nil {
+		t.Fatalf("ServeHealthCheck: %v", err)
+	}
+	b.SetHealthStatus("RPCMethod", HealthNotServing)
+
+	ctx := context.Background()
+	if status, err := a.HealthCheck(ctx, ""); err != nil || status != HealthServing {
+		t.Errorf("HealthCheck of the server = %v, %v, want SERVING", status, err)
+	}
+	if status, err := a.HealthCheck(ctx, "RPCMethod"); err != nil || status != HealthNotServing {
+		t.Errorf("HealthCheck of RPCMethod = %v, %v, want NOT_SERVING", status, err)
+	}
+	if _, err := a.HealthCheck(ctx, "Other"); err == nil {
+		t.Error("HealthCheck of an unknown service succeeded")
+	}
+}
@@ -0,0 +1,84 @@
+package rpcmux
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+)
+
+// ErrNoDuplexes is returned by CallHedged when it is given no duplex to call.
+var ErrNoDuplexes = errors.New("rpcmux: no duplexes to call")
+
+// CallHedged makes the same call over several duplexes connected to replicas of a service and
+// returns the first successful reply, trading extra load for a lower tail latency.
+// The call is sent to duplexes[0] at once, and to each next duplex when the previous ones have
+// not replied within hedge, or have all failed. The calls still running when one succeeds are
+// cancelled. If every call fails, the error of the last one to fail is returned.
+//
+// reply must be a pointer; each call decodes into its own copy, and only the winning one is
+// stored into reply.
+func CallHedged(ctx context.Context, method string, args, reply interface{}, hedge time.Duration, duplexes []*RPCDuplex) error {
+	if len(duplexes) == 0 {
+		return ErrNoDuplexes
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		reply reflect.Value
+		err   error
+	}
+	results := make(chan result, len(duplexes))
+	replyType := reflect.TypeOf(reply).Elem()
+	launch := func(d *RPCDuplex) {
+		r := reflect.New(replyType)
+		go func() {
+			results <- result{reply: r, err: d.CallContext(ctx, method, args, r.Interface())}
+		}()
+	}
+
+	launch(duplexes[0])
+	sent, running := 1, 1
+	timer := time.NewTimer(hedge)
+	defer timer.Stop()
+
+	var err error
+	for running > 0 {
+		select {
+		case <-timer.C:
+			if sent < len(duplexes) {
+				launch(duplexes[sent])
+				sent++
+				running++
+				timer.Reset(hedge)
+			}
+		case res := <-results:
+			running--
+			if res.err == nil {
+				reflect.ValueOf(reply).Elem().Set(res.reply.Elem())
+				return nil
+			}
+			err = res.err
+			if running == 0 && sent < len(duplexes) && ctx.Err() == nil {
+				launch(duplexes[sent])
+				sent++
+				running++
+				resetTimer(timer, hedge)
+			}
+		}
+	}
+	return err
+}
+
+// resetTimer makes t fire after d, dropping a tick it may have sent meanwhile, so that the
+// next duplex is not launched before d has passed.
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
@@ -0,0 +1,99 @@
+package rpcmux
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Replica is a service answering Replica.Echo with its own name after a delay.
+type Replica struct {
+	name  string
+	delay time.Duration
+	fail  bool
+	calls atomic.Int32
+}
+
+func (r *Replica) Echo(_ Person, reply *Person) error {
+	r.calls.Add(1)
+	time.Sleep(r.delay)
+	if r.fail {
+		return errors.New(r.name + " failed")
+	}
+	reply.Name = r.name
+	return nil
+}
+
+// newReplica returns a duplex connected over net.Pipe to a duplex serving r.
+func newReplica(t *testing.T, r *Replica) *RPCDuplex {
+	t.Helper()
+	connA, connB := net.Pipe()
+	server, client := NewRPCDuplex(connA), NewRPCDuplex(connB)
+	t.Cleanup(func() { client.Close(); server.Close() })
+	if err := server.Register(r); err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+	return client
+}
+
+func TestCallHedgedFastPrimary(t *testing.T) {
+	a, b := &Replica{name: "a"}, &Replica{name: "b"}
+	var reply Person
+	err := CallHedged(context.Background(), "Replica.Echo", Person{}, &reply, time.Second,
+		[]*RPCDuplex{newReplica(t, a), newReplica(t, b)})
+	if err != nil || reply.Name != "a" {
+		t.Fatalf("CallHedged = %q, %v, want %q, nil", reply.Name, err, "a")
+	}
+	if n := b.calls.Load(); n != 0 {
+		t.Errorf("hedge sent %d times to a replica although the primary was fast", n)
+	}
+}
+
+func TestCallHedgedSlowPrimary(t *testing.T) {
+	a, b := &Replica{name: "a", delay: time.Second}, &Replica{name: "b"}
+	start := time.Now()
+	var reply Person
+	err := CallHedged(context.Background(), "Replica.Echo", Person{}, &reply, 20*time.Millisecond,
+		[]*RPCDuplex{newReplica(t, a), newReplica(t, b)})
+	if err != nil || reply.Name != "b" {
+		t.Fatalf("CallHedged = %q, %v, want %q, nil", reply.Name, err, "b")
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond || elapsed >= a.delay {
+		t.Errorf("CallHedged took %v, want between the hedge and the primary's delay", elapsed)
+	}
+}
+
+func TestCallHedgedFailingPrimary(t *testing.T) {
+	a, b := &Replica{name: "a", fail: true}, &Replica{name: "b"}
+	start := time.Now()
+	var reply Person
+	err := CallHedged(context.Background(), "Replica.Echo", Person{}, &reply, time.Hour,
+		[]*RPCDuplex{newReplica(t, a), newReplica(t, b)})
+	if err != nil || reply.Name != "b" {
+		t.Fatalf("CallHedged = %q, %v, want %q, nil", reply.Name, err, "b")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("next replica called after %v, want at once", elapsed)
+	}
+}
+
+func TestCallHedgedAllFail(t *testing.T) {
+	a, b := &Replica{name: "a", fail: true}, &Replica{name: "b", fail: true}
+	var reply Person
+	err := CallHedged(context.Background(), "Replica.Echo", Person{}, &reply, time.Hour,
+		[]*RPCDuplex{newReplica(t, a), newReplica(t, b)})
+	if err == nil || err.Error() != "b failed" {
+		t.Errorf("CallHedged = %v, want the error of the last replica", err)
+	}
+}
+
+func TestCallHedgedNoDuplexes(t *testing.T) {
+	var reply Person
+	if err := CallHedged(context.Background(), "Replica.Echo", Person{}, &reply, time.Second, nil); err != ErrNoDuplexes {
+		t.Errorf("CallHedged = %v, want ErrNoDuplexes", err)
+	}
+}
@@ -0,0 +1,50 @@
+package rpcmux
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// ErrHijacked is the error of the calls of a duplex whose connection was taken over by Hijack.
+var ErrHijacked = errors.New("rpcmux: connection hijacked")
+
+// Hijack takes the connection over from the duplex, as http.Hijacker does, so that it can be
+// used for another protocol. It stops the goroutine reading the connection, and returns the
+// connection with the bytes that were read from it but not handled yet; frames already read
+// are dropped. The duplex is closed: its calls fail with ErrHijacked, but the connection is
+// left open. Hijacking a duplex twice returns ErrHijacked.
+//
+// The peer must stop sending frames first, or they are read from the returned connection.
+func (d *RPCDuplex) Hijack() (net.Conn, []byte, error) {
+	rest, err := d.mux.hijack()
+	d.Client.Close()
+	if err != nil {
+		if err != ErrHijacked {
+			d.Conn.Close()
+		}
+		return nil, nil, err
+	}
+	return d.Conn, rest, nil
+}
+
+// hijack stops run and returns the bytes it read but did not handle. Frames being written
+// are written in full first; no frame is written afterwards.
+func (m *demux) hijack() ([]byte, error) {
+	m.wmu.Lock()
+	already := m.hijacked.Swap(true)
+	m.wmu.Unlock()
+	if already {
+		return nil, ErrHijacked
+	}
+
+	// A deadline in the past makes the pending read return at once.
+	m.conn.SetReadDeadline(time.Unix(1, 0))
+	<-m.done
+	m.conn.SetReadDeadline(time.Time{})
+	if m.err != ErrHijacked {
+		// run had stopped before, because the connection failed.
+		return nil, m.err
+	}
+	return m.rest, nil
+}
@@ -0,0 +1,70 @@
+package rpcmux
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHijack(t *testing.T) {
+	connA, connB := net.Pipe()
+	d := NewRPCDuplex(connA)
+	defer d.Close()
+	defer connB.Close()
+
+	// Play the peer: read the hello of d, then send a hello and the start of another protocol.
+	go func() {
+		io.ReadFull(connB, make([]byte, len(helloFrame())))
+		connB.Write(append(helloFrame(), "XYZ"...))
+	}()
+	time.Sleep(20 * time.Millisecond) // let d read what was sent
+
+	conn, rest, err := d.Hijack()
+	if err != nil {
+		t.Fatalf("Hijack: %v", err)
+	}
+	if string(rest) != "XYZ" {
+		t.Errorf("Hijack returned %q, want the unhandled %q", rest, "XYZ")
+	}
+
+	go connB.Write([]byte("more"))
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "more" {
+		t.Errorf("read from the hijacked connection = %q, %v, want %q", buf, err, "more")
+	}
+
+	var reply Person
+	if err := d.Call("RPCMethod.SayHello", Person{}, &reply); !errors.Is(err, ErrHijacked) {
+		t.Errorf("Call after Hijack = %v, want ErrHijacked", err)
+	}
+	if _, _, err := d.Hijack(); err != ErrHijacked {
+		t.Errorf("second Hijack = %v, want ErrHijacked", err)
+	}
+}
+
+// TestHijackBothEnds switches both ends of a duplex over to raw bytes once they agree to.
+func TestHijackBothEnds(t *testing.T) {
+	a, b := newPipe(t)
+	var reply Person
+	if err := a.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+
+	connA, _, err := a.Hijack()
+	if err != nil {
+		t.Fatalf("Hijack: %v", err)
+	}
+	connB, rest, err := b.Hijack()
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("Hijack = %q, %v, want no bytes left", rest, err)
+	}
+	defer connA.Close()
+
+	go connA.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(connB, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("read from the hijacked connection = %q, %v, want %q", buf, err, "ping")
+	}
+}
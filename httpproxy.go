@@ -0,0 +1,97 @@
+package rpcmux
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// DialThroughHTTPProxy connects to targetAddr through the HTTP proxy at proxyAddr using the
+// CONNECT method, and returns the tunnelled connection as a RPCDuplex. auth, if not nil, is sent
+// as basic Proxy-Authorization credentials. If tlsCfg is not nil, a TLS handshake with the target
+// is run over the tunnel; its ServerName defaults to the host of targetAddr.
+func DialThroughHTTPProxy(proxyAddr, targetAddr string, auth *url.Userinfo, tlsCfg *tls.Config, opts ...Option) (*RPCDuplex, error) {
+	conn, err := net.Dial("tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	tunnel, err := httpConnect(conn, targetAddr, auth)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if tlsCfg != nil {
+		cfg := tlsCfg.Clone()
+		if cfg.ServerName == "" {
+			if host, _, err := net.SplitHostPort(targetAddr); err == nil {
+				cfg.ServerName = host
+			}
+		}
+		tc := tls.Client(tunnel, cfg)
+		if err := tc.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tunnel = tc
+	}
+
+	d, err := newRPCDuplex(tunnel, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// httpConnect asks the HTTP proxy on conn to open a tunnel to targetAddr.
+func httpConnect(conn net.Conn, targetAddr string, auth *url.Userinfo) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Host: targetAddr},
+		Host:   targetAddr,
+		Header: make(http.Header),
+	}
+	if auth != nil {
+		password, _ := auth.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(auth.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("rpcmux: proxy refused CONNECT to %s: %s", targetAddr, resp.Status)
+	}
+
+	if br.Buffered() > 0 {
+		// The target already started talking; keep what the response reader buffered.
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a net.Conn whose reads are served from r first.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// NetConn returns the wrapped connection.
+func (c *bufferedConn) NetConn() net.Conn {
+	return c.Conn
+}
@@ -0,0 +1,99 @@
+package rpcmux
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// connectProxy returns an HTTP proxy tunnelling CONNECT requests that carry the
+// Proxy-Authorization header wantAuth, and answering the others with 407.
+func connectProxy(t *testing.T, wantAuth string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "only CONNECT is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Proxy-Authorization") != wantAuth {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer target.Close()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go io.Copy(target, conn)
+		io.Copy(conn, target)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDialThroughHTTPProxy(t *testing.T) {
+	dl := listenTCP(t)
+	serveAccepted(t, dl)
+	// Basic credentials of "anto:secret".
+	proxy := connectProxy(t, "Basic YW50bzpzZWNyZXQ=")
+
+	d, err := DialThroughHTTPProxy(proxy.Listener.Addr().String(), dl.Addr().String(), url.UserPassword("anto", "secret"), nil)
+	if err != nil {
+		t.Fatalf("DialThroughHTTPProxy: %v", err)
+	}
+	defer d.Close()
+	var reply Person
+	if err := d.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply); err != nil || reply.Name != "Anto" {
+		t.Errorf("Call through the proxy = %+v, %v", reply, err)
+	}
+}
+
+func TestDialThroughHTTPProxyRefused(t *testing.T) {
+	dl := listenTCP(t)
+	proxy := connectProxy(t, "Basic YW50bzpzZWNyZXQ=")
+
+	for _, auth := range []*url.Userinfo{nil, url.UserPassword("anto", "wrong")} {
+		_, err := DialThroughHTTPProxy(proxy.Listener.Addr().String(), dl.Addr().String(), auth, nil)
+		if err == nil || !strings.Contains(err.Error(), "407") {
+			t.Errorf("DialThroughHTTPProxy with credentials %v = %v, want the 407 status", auth, err)
+		}
+	}
+}
+
+// TestHTTPConnectBuffered sends the first bytes of the target in the same read as the
+// response to CONNECT: they must be read from the returned conn.
+func TestHTTPConnectBuffered(t *testing.T) {
+	conn, proxy := net.Pipe()
+	defer conn.Close()
+	go func() {
+		defer proxy.Close()
+		if _, err := http.ReadRequest(bufio.NewReader(proxy)); err != nil {
+			return
+		}
+		io.WriteString(proxy, "HTTP/1.1 200 OK\r\n\r\nearly")
+		io.WriteString(proxy, " bytes")
+	}()
+
+	tunnel, err := httpConnect(conn, "target:1", nil)
+	if err != nil {
+		t.Fatalf("httpConnect: %v", err)
+	}
+	if _, ok := tunnel.(*bufferedConn); !ok {
+		t.Fatalf("httpConnect returned a %T, want a *bufferedConn", tunnel)
+	}
+	got, err := io.ReadAll(tunnel)
+	if string(got) != "early bytes" {
+		t.Errorf("read from the tunnel = %q, %v, want %q", got, err, "early bytes")
+	}
+}
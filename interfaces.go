@@ -0,0 +1,24 @@
+package rpcmux
+
+import "context"
+
+// Client is the calling side of a RPCDuplex. Code that only makes calls can depend on
+// Client instead of *RPCDuplex, so that a mock.MockDuplex can stand in for it in tests.
+type Client interface {
+	Call(serviceMethod string, args interface{}, reply interface{}) error
+	CallContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error
+	Close() error
+}
+
+var _ Client = (*RPCDuplex)(nil)
+
+// Server is the serving side of a RPCDuplex. Code that only sets up and runs services can
+// depend on Server instead of *RPCDuplex.
+type Server interface {
+	Register(rcvr interface{}) error
+	RegisterName(name string, rcvr interface{}) error
+	Serve() error
+	Shutdown(ctx context.Context) error
+}
+
+var _ Server = (*RPCDuplex)(nil)
@@ -0,0 +1,104 @@
+// Package poll queues values on a server for clients that fetch them by long-polling it.
+// It is shared by the packages that deliver events to the peers calling them.
+package poll
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// Timeout is how long a poll waits for values before returning empty handed.
+	Timeout = 10 * time.Second
+
+	// StaleTimeout is after how long without polls a queue is considered abandoned.
+	StaleTimeout = 3 * Timeout
+)
+
+// staleTimeout is StaleTimeout, shortened by the tests.
+var staleTimeout = StaleTimeout
+
+// Queue queues the values of one client until they are polled. It is safe for concurrent use.
+type Queue[T any] struct {
+	ready chan struct{} // signalled when values are queued or the queue is closed
+	stale *time.Timer   // fires once the queue was not polled for StaleTimeout, nil if never
+
+	mu     sync.Mutex
+	items  []T
+	closed bool
+}
+
+// NewQueue returns an empty Queue. Unless onStale is nil, it is called from its own goroutine
+// when the queue has not been polled for StaleTimeout, even if nothing was pushed meanwhile;
+// it should forget the queue and close it.
+func NewQueue[T any](onStale func()) *Queue[T] {
+	q := &Queue[T]{ready: make(chan struct{}, 1)}
+	if onStale != nil {
+		q.stale = time.AfterFunc(staleTimeout, onStale)
+	}
+	return q
+}
+
+// Push queues v for the next poll.
+func (q *Queue[T]) Push(v T) {
+	q.mu.Lock()
+	q.items = append(q.items, v)
+	q.mu.Unlock()
+	q.signal()
+}
+
+// Pop waits until values are queued and returns them, or until timeout fires.
+// It reports false once the queue is closed.
+func (q *Queue[T]) Pop(timeout <-chan time.Time) ([]T, bool) {
+	if q.stale == nil {
+		return q.wait(timeout)
+	}
+	// A queue being polled is not stale; it has StaleTimeout again once the poll is over.
+	q.stale.Stop()
+	items, ok := q.wait(timeout)
+	q.mu.Lock()
+	if !q.closed {
+		q.stale.Reset(staleTimeout)
+	}
+	q.mu.Unlock()
+	return items, ok
+}
+
+func (q *Queue[T]) wait(timeout <-chan time.Time) ([]T, bool) {
+	for {
+		q.mu.Lock()
+		items, closed := q.items, q.closed
+		q.items = nil
+		q.mu.Unlock()
+
+		if closed {
+			return nil, false
+		}
+		if len(items) > 0 {
+			return items, true
+		}
+		select {
+		case <-q.ready:
+		case <-timeout:
+			return nil, true
+		}
+	}
+}
+
+// Close drops the queued values and makes the pending and later polls report false.
+func (q *Queue[T]) Close() {
+	q.mu.Lock()
+	q.items, q.closed = nil, true
+	if q.stale != nil {
+		q.stale.Stop()
+	}
+	q.mu.Unlock()
+	q.signal()
+}
+
+func (q *Queue[T]) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
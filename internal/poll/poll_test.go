@@ -0,0 +1,49 @@
+package poll
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueuePushPop(t *testing.T) {
+	q := NewQueue[int](nil)
+	q.Push(1)
+	q.Push(2)
+	if items, ok := q.Pop(nil); !ok || len(items) != 2 || items[0] != 1 || items[1] != 2 {
+		t.Fatalf("Pop = %v, %v, want [1 2], true", items, ok)
+	}
+	if items, ok := q.Pop(time.After(10 * time.Millisecond)); !ok || len(items) != 0 {
+		t.Fatalf("Pop of an empty queue = %v, %v, want [], true", items, ok)
+	}
+
+	done := make(chan bool)
+	go func() {
+		_, ok := q.Pop(nil)
+		done <- ok
+	}()
+	q.Close()
+	if ok := <-done; ok {
+		t.Error("Pop of a closed queue reported true")
+	}
+}
+
+// TestQueueStale leaves a queue without polls or pushes: it must still be reported stale.
+func TestQueueStale(t *testing.T) {
+	defer func(d time.Duration) { staleTimeout = d }(staleTimeout)
+	staleTimeout = 20 * time.Millisecond
+
+	stale := make(chan struct{})
+	q := NewQueue[int](func() { close(stale) })
+	q.Pop(time.After(2 * staleTimeout)) // not stale while polled
+
+	select {
+	case <-stale:
+		t.Fatal("queue reported stale while it was polled")
+	default:
+	}
+	select {
+	case <-stale:
+	case <-time.After(time.Second):
+		t.Fatal("queue not reported stale")
+	}
+}
@@ -0,0 +1,23 @@
+// Package rpctest provides the test fixture shared by the packages built on rpcmux.
+package rpctest
+
+import (
+	"net"
+	"testing"
+
+	rpcmux "github.com/atang152/test_duplex"
+)
+
+// Serve returns a duplex connected over net.Pipe to a duplex on which register registers
+// the services under test. That duplex serves them, and both are closed when t ends.
+func Serve(t testing.TB, register func(*rpcmux.RPCDuplex) error) *rpcmux.RPCDuplex {
+	t.Helper()
+	connA, connB := net.Pipe()
+	server, client := rpcmux.NewRPCDuplex(connA), rpcmux.NewRPCDuplex(connB)
+	t.Cleanup(func() { client.Close(); server.Close() })
+	if err := register(server); err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+	return client
+}
@@ -0,0 +1,116 @@
+package rpcmux
+
+import (
+	"go/token"
+	"reflect"
+	"sort"
+)
+
+// introspectionServiceName is the reserved service under which every RPCDuplex describes itself.
+// The peer lists the served methods by calling "_rpcmux.ListMethods" with an empty struct{}
+// argument and a *[]MethodInfo reply.
+const introspectionServiceName = "_rpcmux"
+
+// MethodInfo describes a method served by the rpc.Server of a RPCDuplex.
+type MethodInfo struct {
+	Name         string // "Service.Method"
+	RequestType  string // Go type of the argument
+	ResponseType string // Go type of the reply, without the pointer
+}
+
+// ListMethods returns the methods registered with Register or RegisterName, sorted by name.
+func (d *RPCDuplex) ListMethods() []MethodInfo {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	out := make([]MethodInfo, 0, len(d.methods))
+	for _, m := range d.methods {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// RegisterName is like Register but uses the provided name for the service instead of the
+// receiver's concrete type. The methods it publishes are listed by ListMethods.
+func (d *RPCDuplex) RegisterName(name string, rcvr interface{}) error {
+	if err := d.Server.RegisterName(name, rcvr); err != nil {
+		return err
+	}
+	d.recordMethods(name, rcvr)
+	return nil
+}
+
+// recordMethods remembers the methods of rcvr that net/rpc publishes under the service name.
+func (d *RPCDuplex) recordMethods(name string, rcvr interface{}) {
+	typ := reflect.TypeOf(rcvr)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.methods == nil {
+		d.methods = make(map[string]MethodInfo)
+		d.argTypes = make(map[string]reflect.Type)
+	}
+	for i := 0; i < typ.NumMethod(); i++ {
+		m := typ.Method(i)
+		if !isRPCMethod(m) {
+			continue
+		}
+		argType := m.Type.In(1)
+		if argType.Kind() == reflect.Pointer {
+			argType = argType.Elem()
+		}
+		info := MethodInfo{
+			Name:         name + "." + m.Name,
+			RequestType:  m.Type.In(1).String(),
+			ResponseType: m.Type.In(2).Elem().String(),
+		}
+		d.methods[info.Name] = info
+		d.argTypes[info.Name] = argType
+	}
+}
+
+// newArgs returns a pointer to a new argument of method, as net/rpc passes to
+// ReadRequestBody, or nil if method was not registered.
+func (d *RPCDuplex) newArgs(method string) interface{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if t := d.argTypes[method]; t != nil {
+		return reflect.New(t).Interface()
+	}
+	return nil
+}
+
+var typeOfError = reflect.TypeOf((*error)(nil)).Elem()
+
+// isRPCMethod reports whether net/rpc publishes m, that is whether it looks schematically like
+// func (t *T) MethodName(argType T1, replyType *T2) error with exported or builtin T1 and T2.
+func isRPCMethod(m reflect.Method) bool {
+	if !m.IsExported() || m.Type.NumIn() != 3 || m.Type.NumOut() != 1 {
+		return false
+	}
+	argType, replyType := m.Type.In(1), m.Type.In(2)
+	return isExportedOrBuiltinType(argType) &&
+		replyType.Kind() == reflect.Pointer && isExportedOrBuiltinType(replyType) &&
+		m.Type.Out(0) == typeOfError
+}
+
+func isExportedOrBuiltinType(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return token.IsExported(t.Name()) || t.PkgPath() == ""
+}
+
+// introspection is the "_rpcmux" service.
+type introspection struct {
+	d *RPCDuplex
+}
+
+// ListMethods replies with the methods served by the duplex.
+func (s *introspection) ListMethods(_ struct{}, reply *[]MethodInfo) error {
+	*reply = s.d.ListMethods()
+	return nil
+}
@@ -0,0 +1,22 @@
+package rpcmux
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListMethods(t *testing.T) {
+	a, b := newPipe(t)
+	want := []MethodInfo{{Name: "RPCMethod.SayHello", RequestType: "rpcmux.Person", ResponseType: "rpcmux.Person"}}
+
+	if got := b.ListMethods(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ListMethods = %v, want %v", got, want)
+	}
+	var got []MethodInfo
+	if err := a.Call(introspectionServiceName+".ListMethods", struct{}{}, &got); err != nil {
+		t.Fatalf("Call of ListMethods: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListMethods of the peer = %v, want %v", got, want)
+	}
+}
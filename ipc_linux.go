@@ -0,0 +1,21 @@
+//go:build linux
+
+package rpcmux
+
+import "net"
+
+// NewRPCDuplexIPC connects to the local IPC endpoint name using the best transport for the OS:
+// a Unix abstract socket on Linux, a Unix domain socket file on other Unix systems and a
+// named pipe on Windows. The peer listens with ListenRPCDuplexIPC.
+func NewRPCDuplexIPC(name string, opts ...Option) (*RPCDuplex, error) {
+	return NewRPCDuplexUnixAbstract(name, opts...)
+}
+
+// ListenRPCDuplexIPC listens on the local IPC endpoint name for NewRPCDuplexIPC.
+func ListenRPCDuplexIPC(name string, opts ...Option) (*DuplexListener, error) {
+	l, err := net.Listen("unix", "@"+name)
+	if err != nil {
+		return nil, err
+	}
+	return ListenRPCDuplex(l, opts...), nil
+}
@@ -0,0 +1,34 @@
+package rpcmux
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestIPC(t *testing.T) {
+	name := fmt.Sprintf("rpcmux-%d-ipc", os.Getpid())
+	dl, err := ListenRPCDuplexIPC(name)
+	if err != nil {
+		t.Fatalf("ListenRPCDuplexIPC: %v", err)
+	}
+	serveAccepted(t, dl)
+
+	d, err := NewRPCDuplexIPC(name)
+	if err != nil {
+		t.Fatalf("NewRPCDuplexIPC: %v", err)
+	}
+	defer d.Close()
+	var reply Person
+	if err := d.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply); err != nil || reply.Name != "Anto" {
+		t.Errorf("Call = %+v, %v", reply, err)
+	}
+
+	if dl, err := ListenRPCDuplexIPC(name); err == nil {
+		dl.Close()
+		t.Error("ListenRPCDuplexIPC of a name in use succeeded")
+	}
+	if _, err := NewRPCDuplexIPC(name + "-absent"); err == nil {
+		t.Error("NewRPCDuplexIPC of a name nobody listens on succeeded")
+	}
+}
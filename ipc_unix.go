@@ -0,0 +1,50 @@
+//go:build unix && !linux
+
+package rpcmux
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// NewRPCDuplexIPC connects to the local IPC endpoint name using the best transport for the OS:
+// a Unix abstract socket on Linux, a Unix domain socket file on other Unix systems and a
+// named pipe on Windows. The peer listens with ListenRPCDuplexIPC.
+func NewRPCDuplexIPC(name string, opts ...Option) (*RPCDuplex, error) {
+	conn, err := net.Dial("unix", ipcSocketPath(name))
+	if err != nil {
+		return nil, err
+	}
+	d, err := newRPCDuplex(conn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ListenRPCDuplexIPC listens on the local IPC endpoint name for NewRPCDuplexIPC.
+// A socket file left behind by a process that exited without closing its listener is replaced.
+func ListenRPCDuplexIPC(name string, opts ...Option) (*DuplexListener, error) {
+	path := ipcSocketPath(name)
+	l, err := net.Listen("unix", path)
+	if errors.Is(err, syscall.EADDRINUSE) {
+		if conn, dialErr := net.Dial("unix", path); dialErr == nil {
+			// Somebody is listening: the address really is in use.
+			conn.Close()
+			return nil, err
+		}
+		os.Remove(path)
+		l, err = net.Listen("unix", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ListenRPCDuplex(l, opts...), nil
+}
+
+func ipcSocketPath(name string) string {
+	return filepath.Join(os.TempDir(), name+".sock")
+}
@@ -0,0 +1,40 @@
+//go:build unix && !linux
+
+package rpcmux
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+)
+
+// TestIPCStaleSocket listens on a name whose socket file was left behind by a listener
+// closed without removing it.
+func TestIPCStaleSocket(t *testing.T) {
+	name := fmt.Sprintf("rpcmux-%d-stale", os.Getpid())
+	l, err := net.Listen("unix", ipcSocketPath(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	if _, err := os.Stat(ipcSocketPath(name)); err != nil {
+		t.Fatalf("socket file not left behind: %v", err)
+	}
+
+	dl, err := ListenRPCDuplexIPC(name)
+	if err != nil {
+		t.Fatalf("ListenRPCDuplexIPC over a stale socket file: %v", err)
+	}
+	serveAccepted(t, dl)
+	d, err := NewRPCDuplexIPC(name)
+	if err != nil {
+		t.Fatalf("NewRPCDuplexIPC: %v", err)
+	}
+	defer d.Close()
+	var reply Person
+	if err := d.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply); err != nil {
+		t.Errorf("Call: %v", err)
+	}
+}
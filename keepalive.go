@@ -0,0 +1,54 @@
+package rpcmux
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// ErrNotTCPConn is returned by the keep-alive methods when the duplex is not over a *net.TCPConn.
+var ErrNotTCPConn = errors.New("rpcmux: underlying connection is not a *net.TCPConn")
+
+// WithKeepAlive enables TCP keep-alives with the given period when the duplex is created,
+// so idle connections are not silently dropped by middleboxes.
+// It has no effect if the connection is not a *net.TCPConn.
+func WithKeepAlive(period time.Duration) Option {
+	return func(d *RPCDuplex) {
+		if d.SetKeepAlive(true) == nil {
+			d.SetKeepAlivePeriod(period)
+		}
+	}
+}
+
+// SetKeepAlive enables or disables TCP keep-alives on the underlying connection.
+func (d *RPCDuplex) SetKeepAlive(enabled bool) error {
+	tc, ok := tcpConn(d.Conn)
+	if !ok {
+		return ErrNotTCPConn
+	}
+	return tc.SetKeepAlive(enabled)
+}
+
+// SetKeepAlivePeriod sets the period between TCP keep-alives on the underlying connection.
+func (d *RPCDuplex) SetKeepAlivePeriod(period time.Duration) error {
+	tc, ok := tcpConn(d.Conn)
+	if !ok {
+		return ErrNotTCPConn
+	}
+	return tc.SetKeepAlivePeriod(period)
+}
+
+// tcpConn returns the *net.TCPConn underneath conn, looking through
+// wrappers that expose the connection they wrap with a NetConn method.
+func tcpConn(conn net.Conn) (*net.TCPConn, bool) {
+	for {
+		switch c := conn.(type) {
+		case *net.TCPConn:
+			return c, true
+		case interface{ NetConn() net.Conn }:
+			conn = c.NetConn()
+		default:
+			return nil, false
+		}
+	}
+}
@@ -0,0 +1,61 @@
+//go:build linux
+
+package rpcmux
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// sockopt returns the value of an integer socket option of conn.
+func sockopt(t *testing.T, conn *net.TCPConn, level, opt int) int {
+	t.Helper()
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v int
+	var serr error
+	if err := raw.Control(func(fd uintptr) { v, serr = unix.GetsockoptInt(int(fd), level, opt) }); err != nil {
+		t.Fatal(err)
+	}
+	if serr != nil {
+		t.Fatal(serr)
+	}
+	return v
+}
+
+func TestWithKeepAlive(t *testing.T) {
+	dl := listenTCP(t)
+	serveAccepted(t, dl)
+	conn, err := net.Dial("tcp", dl.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := conn.(*net.TCPConn)
+	d := NewRPCDuplex(conn, WithKeepAlive(42*time.Second))
+	defer d.Close()
+
+	if got := sockopt(t, tc, unix.SOL_SOCKET, unix.SO_KEEPALIVE); got != 1 {
+		t.Errorf("SO_KEEPALIVE = %d, want 1", got)
+	}
+	if got := sockopt(t, tc, unix.IPPROTO_TCP, unix.TCP_KEEPIDLE); got != 42 {
+		t.Errorf("TCP_KEEPIDLE = %d, want 42", got)
+	}
+
+	if err := d.SetKeepAlivePeriod(7 * time.Second); err != nil {
+		t.Fatalf("SetKeepAlivePeriod: %v", err)
+	}
+	if got := sockopt(t, tc, unix.IPPROTO_TCP, unix.TCP_KEEPIDLE); got != 7 {
+		t.Errorf("TCP_KEEPIDLE after SetKeepAlivePeriod = %d, want 7", got)
+	}
+	if err := d.SetKeepAlive(false); err != nil {
+		t.Fatalf("SetKeepAlive: %v", err)
+	}
+	if got := sockopt(t, tc, unix.SOL_SOCKET, unix.SO_KEEPALIVE); got != 0 {
+		t.Errorf("SO_KEEPALIVE after SetKeepAlive(false) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,45 @@
+package rpcmux
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestKeepAliveNotTCP(t *testing.T) {
+	a, _ := newPipe(t, WithKeepAlive(time.Second))
+	if err := a.SetKeepAlive(true); !errors.Is(err, ErrNotTCPConn) {
+		t.Errorf("SetKeepAlive over net.Pipe = %v, want ErrNotTCPConn", err)
+	}
+	if err := a.SetKeepAlivePeriod(time.Second); !errors.Is(err, ErrNotTCPConn) {
+		t.Errorf("SetKeepAlivePeriod over net.Pipe = %v, want ErrNotTCPConn", err)
+	}
+	// WithKeepAlive has no effect, and the duplex works.
+	var reply Person
+	if err := a.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply); err != nil {
+		t.Errorf("Call: %v", err)
+	}
+}
+
+func TestTCPConnUnwrap(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	wrapped := &bufferedConn{Conn: conn, r: bufio.NewReader(conn)}
+	if tc, ok := tcpConn(wrapped); !ok || tc != conn {
+		t.Errorf("tcpConn of a wrapped *net.TCPConn = %v, %v, want the *net.TCPConn", tc, ok)
+	}
+	if _, ok := tcpConn(&bufferedConn{Conn: wrapped}); !ok {
+		t.Error("tcpConn does not look through two wrappers")
+	}
+}
@@ -0,0 +1,204 @@
+// Package kv provides a key-value store shared by processes connected over RPCDuplex.
+//
+// One end serves a Server holding the values; the other ends set, get and watch them with
+// a Store, which makes it a simple way of sharing configuration. Watchers receive their
+// events by polling the Server, so that a Store needs no service of its own on its end.
+package kv
+
+import (
+	"context"
+	"errors"
+	"net/rpc"
+	"strings"
+	"sync"
+	"time"
+
+	rpcmux "github.com/atang152/test_duplex"
+	"github.com/atang152/test_duplex/internal/poll"
+)
+
+// serviceName is the name the Server is registered under.
+const serviceName = "KV"
+
+// Errors returned by Store.
+var (
+	ErrNotFound     = errors.New("kv: key not found")
+	ErrUnknownWatch = errors.New("kv: unknown watch")
+)
+
+// KVEvent describes a change of the value of Key.
+type KVEvent struct {
+	Key   string
+	Value []byte
+}
+
+// Server holds the values of a Store.
+type Server struct {
+	values sync.Map // string to []byte
+
+	mu       sync.Mutex
+	watchers map[uint64]*watcher
+	nextID   uint64
+}
+
+// NewServer returns an empty Server.
+func NewServer() *Server {
+	return &Server{watchers: make(map[uint64]*watcher)}
+}
+
+// Serve registers s on d so that the peer at the other end can use the store.
+// The same Server is served on the duplex of every peer sharing the store.
+func (s *Server) Serve(d *rpcmux.RPCDuplex) error {
+	return d.RegisterName(serviceName, &kvService{s})
+}
+
+func (s *Server) lookup(id uint64) *watcher {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.watchers[id]
+}
+
+// remove cancels the watch id.
+func (s *Server) remove(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := s.watchers[id]
+	if w == nil {
+		return ErrUnknownWatch
+	}
+	delete(s.watchers, id)
+	w.queue.Close()
+	return nil
+}
+
+// watcher is one watch, queueing its events until they are polled.
+type watcher struct {
+	prefix string
+	queue  *poll.Queue[KVEvent]
+}
+
+// kvService is the RPC facade of a Server.
+type kvService struct {
+	s *Server
+}
+
+// Set stores ev.Value under ev.Key and notifies the watchers of matching prefixes.
+func (k *kvService) Set(ev KVEvent, _ *struct{}) error {
+	k.s.values.Store(ev.Key, ev.Value)
+
+	k.s.mu.Lock()
+	defer k.s.mu.Unlock()
+
+	for _, w := range k.s.watchers {
+		if strings.HasPrefix(ev.Key, w.prefix) {
+			w.queue.Push(ev)
+		}
+	}
+	return nil
+}
+
+// Get replies with the value stored under key.
+func (k *kvService) Get(key string, value *[]byte) error {
+	v, ok := k.s.values.Load(key)
+	if !ok {
+		return ErrNotFound
+	}
+	*value = v.([]byte)
+	return nil
+}
+
+// Watch starts watching the keys beginning with prefix, replying with the ID to poll with Next.
+// The watch is dropped once it is not polled for poll.StaleTimeout.
+func (k *kvService) Watch(prefix string, id *uint64) error {
+	k.s.mu.Lock()
+	defer k.s.mu.Unlock()
+
+	k.s.nextID++
+	watchID := k.s.nextID
+	k.s.watchers[watchID] = &watcher{
+		prefix: prefix,
+		queue:  poll.NewQueue[KVEvent](func() { k.s.remove(watchID) }),
+	}
+	*id = watchID
+	return nil
+}
+
+// Next waits for the events of the watch id.
+// It replies with no events if no matching key was set within poll.Timeout.
+func (k *kvService) Next(id uint64, events *[]KVEvent) error {
+	w := k.s.lookup(id)
+	if w == nil {
+		return ErrUnknownWatch
+	}
+	timer := time.NewTimer(poll.Timeout)
+	defer timer.Stop()
+
+	queued, ok := w.queue.Pop(timer.C)
+	if !ok {
+		return ErrUnknownWatch
+	}
+	*events = queued
+	return nil
+}
+
+// Unwatch cancels the watch id.
+func (k *kvService) Unwatch(id uint64, _ *struct{}) error {
+	return k.s.remove(id)
+}
+
+// Store is a client of the Server served at the other end of a RPCDuplex.
+type Store struct {
+	d *rpcmux.RPCDuplex
+}
+
+// New returns a Store using the Server served at the other end of d.
+func New(d *rpcmux.RPCDuplex) *Store {
+	return &Store{d: d}
+}
+
+// Set stores value under key.
+func (s *Store) Set(ctx context.Context, key string, value []byte) error {
+	return s.d.CallContext(ctx, serviceName+".Set", KVEvent{Key: key, Value: value}, &struct{}{})
+}
+
+// Get returns the value stored under key, or ErrNotFound if there is none.
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	if err := s.d.CallContext(ctx, serviceName+".Get", key, &value); err != nil {
+		if err == rpc.ServerError(ErrNotFound.Error()) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return value, nil
+}
+
+// Watch returns a channel receiving an event every time a key beginning with prefix is set.
+// The channel is closed once ctx is done or the Server cannot be reached.
+func (s *Store) Watch(ctx context.Context, prefix string) (<-chan KVEvent, error) {
+	var id uint64
+	if err := s.d.CallContext(ctx, serviceName+".Watch", prefix, &id); err != nil {
+		return nil, err
+	}
+	ch := make(chan KVEvent)
+	go func() {
+		defer close(ch)
+		defer s.d.Call(serviceName+".Unwatch", id, &struct{}{})
+
+		for {
+			var events []KVEvent
+			if err := s.d.CallContext(ctx, serviceName+".Next", id, &events); err != nil {
+				return
+			}
+			for _, ev := range events {
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
@@ -0,0 +1,57 @@
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/atang152/test_duplex/internal/rpctest"
+)
+
+func TestSetGet(t *testing.T) {
+	st := New(rpctest.Serve(t, NewServer().Serve))
+	ctx := context.Background()
+
+	if _, err := st.Get(ctx, "a"); err != ErrNotFound {
+		t.Fatalf("Get of a missing key = %v, want ErrNotFound", err)
+	}
+	if err := st.Set(ctx, "a", []byte("1")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if v, err := st.Get(ctx, "a"); err != nil || string(v) != "1" {
+		t.Errorf("Get = %q, %v, want %q, nil", v, err, "1")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	s := NewServer()
+	watcher, setter := New(rpctest.Serve(t, s.Serve)), New(rpctest.Serve(t, s.Serve))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := watcher.Watch(ctx, "config/")
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	setter.Set(ctx, "other", []byte("x"))
+	setter.Set(ctx, "config/level", []byte("debug"))
+	select {
+	case ev := <-events:
+		if ev.Key != "config/level" || string(ev.Value) != "debug" {
+			t.Errorf("event = %s=%q, want config/level=%q", ev.Key, ev.Value, "debug")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("event received after the watch was cancelled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("events not closed once ctx was done")
+	}
+}
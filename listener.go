@@ -0,0 +1,102 @@
+package rpcmux
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// DuplexListener accepts connections from a net.Listener and wraps each one in a RPCDuplex.
+// Its methods may be called from multiple goroutines, like those of net.Listener.
+type DuplexListener struct {
+	l    net.Listener
+	opts []Option
+
+	ready chan *RPCDuplex // duplexes whose options were applied, waiting for Accept
+	errs  chan error      // errors of the net.Listener, for Accept to return
+
+	closeOnce sync.Once
+	closing   chan struct{} // closed by Close
+	stopped   chan struct{} // closed once the net.Listener is closed, after err is set
+	err       error         // error of the net.Listener once it is closed
+}
+
+// ListenRPCDuplex returns a DuplexListener accepting connections from l.
+// Every accepted RPCDuplex is created with opts.
+func ListenRPCDuplex(l net.Listener, opts ...Option) *DuplexListener {
+	dl := &DuplexListener{
+		l:       l,
+		opts:    opts,
+		ready:   make(chan *RPCDuplex),
+		errs:    make(chan error),
+		closing: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go dl.run()
+	return dl
+}
+
+// run accepts connections until the net.Listener is closed, creating the duplex of each one
+// in its own goroutine, so that a peer slow to send its PROXY protocol header does not hold
+// up the others.
+func (dl *DuplexListener) run() {
+	defer close(dl.stopped)
+	for {
+		conn, err := dl.l.Accept()
+		if err == nil {
+			go dl.open(conn)
+			continue
+		}
+		if errors.Is(err, net.ErrClosed) {
+			dl.err = err
+			return
+		}
+		select {
+		case dl.errs <- err:
+		case <-dl.closing:
+			dl.err = err
+			return
+		}
+	}
+}
+
+func (dl *DuplexListener) open(conn net.Conn) {
+	d, err := newRPCDuplex(conn, dl.opts...)
+	if err != nil {
+		// newRPCDuplex closed conn.
+		return
+	}
+	select {
+	case dl.ready <- d:
+	case <-dl.closing:
+		d.Close()
+	}
+}
+
+// Accept waits for the next connection and returns it as a ready to use RPCDuplex.
+// Connections for which an option fails, such as a peer sending no valid PROXY protocol
+// header, are closed and skipped; only the errors of the net.Listener are returned.
+// Options are applied to several connections at once, so connections are not necessarily
+// returned in the order they were accepted.
+func (dl *DuplexListener) Accept() (*RPCDuplex, error) {
+	select {
+	case d := <-dl.ready:
+		return d, nil
+	case err := <-dl.errs:
+		return nil, err
+	case <-dl.stopped:
+		return nil, dl.err
+	}
+}
+
+// Close stops accepting new connections. RPCDuplexes that were already accepted stay open;
+// those not returned by Accept yet are closed.
+func (dl *DuplexListener) Close() error {
+	dl.closeOnce.Do(func() { close(dl.closing) })
+	return dl.l.Close()
+}
+
+// Addr returns the listener's network address.
+func (dl *DuplexListener) Addr() net.Addr {
+	return dl.l.Addr()
+}
@@ -0,0 +1,135 @@
+// Package mock provides an in-process stand-in for RPCDuplex, so that service clients can be
+// unit tested without a server at the other end.
+//
+// A MockDuplex answers calls from a table of handlers and from expectations set with Expect.
+// Replies are copied into the caller's reply with encoding/gob, as they would be over a real
+// duplex, so a reply type that could not cross the wire fails here too.
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"net/rpc"
+	"reflect"
+	"strings"
+	"sync"
+
+	rpcmux "github.com/atang152/test_duplex"
+)
+
+// Handler answers a call, filling reply from args like a method of a registered receiver.
+type Handler func(args interface{}, reply interface{}) error
+
+// MockDuplex resolves calls in-process instead of sending them to a peer.
+// It implements rpcmux.Client.
+type MockDuplex struct {
+	mu           sync.Mutex
+	handlers     map[string]Handler
+	expectations []*expectation
+	closed       bool
+}
+
+type expectation struct {
+	method string
+	input  interface{}
+	output interface{}
+	met    bool
+}
+
+var _ rpcmux.Client = (*MockDuplex)(nil)
+
+// New returns a MockDuplex with no handlers and no expectations.
+func New() *MockDuplex {
+	return &MockDuplex{handlers: make(map[string]Handler)}
+}
+
+// Handle makes h answer the calls to serviceMethod that match no expectation.
+func (m *MockDuplex) Handle(serviceMethod string, h Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[serviceMethod] = h
+}
+
+// Expect expects one call to serviceMethod with args deeply equal to input, and answers it
+// with output. Expectations are matched in the order they were set.
+func (m *MockDuplex) Expect(serviceMethod string, input interface{}, output interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations = append(m.expectations, &expectation{method: serviceMethod, input: input, output: output})
+}
+
+// ExpectationsWereMet returns an error listing the expectations no call has matched yet.
+func (m *MockDuplex) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var unmet []string
+	for _, e := range m.expectations {
+		if !e.met {
+			unmet = append(unmet, fmt.Sprintf("%s(%+v)", e.method, e.input))
+		}
+	}
+	if len(unmet) > 0 {
+		return fmt.Errorf("mock: expected calls not made: %s", strings.Join(unmet, ", "))
+	}
+	return nil
+}
+
+// Call answers serviceMethod with the first unmet matching expectation, or else with its handler.
+// It returns an error for calls that neither can answer.
+func (m *MockDuplex) Call(serviceMethod string, args interface{}, reply interface{}) error {
+	return m.CallContext(context.Background(), serviceMethod, args, reply)
+}
+
+// CallContext is like Call but returns ctx.Err() instead if ctx is already done.
+func (m *MockDuplex) CallContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return rpc.ErrShutdown
+	}
+	for _, e := range m.expectations {
+		if !e.met && e.method == serviceMethod && reflect.DeepEqual(e.input, args) {
+			e.met = true
+			m.mu.Unlock()
+			return copyReply(e.output, reply)
+		}
+	}
+	h := m.handlers[serviceMethod]
+	m.mu.Unlock()
+
+	if h == nil {
+		return fmt.Errorf("mock: unexpected call to %s(%+v)", serviceMethod, args)
+	}
+	return h(args, reply)
+}
+
+// Close makes every later call fail with rpc.ErrShutdown, like a closed RPCDuplex.
+func (m *MockDuplex) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return rpc.ErrShutdown
+	}
+	m.closed = true
+	return nil
+}
+
+// copyReply copies output into reply through encoding/gob.
+func copyReply(output interface{}, reply interface{}) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(output); err != nil {
+		return fmt.Errorf("mock: encoding reply: %w", err)
+	}
+	if err := gob.NewDecoder(&buf).Decode(reply); err != nil {
+		return fmt.Errorf("mock: decoding reply: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package mock
+
+import (
+	"errors"
+	"net/rpc"
+	"strings"
+	"testing"
+
+	rpcmux "github.com/atang152/test_duplex"
+)
+
+func TestExpect(t *testing.T) {
+	m := New()
+	m.Expect("RPCMethod.SayHello", rpcmux.Person{Name: "Anto"}, rpcmux.Person{Name: "first"})
+	m.Expect("RPCMethod.SayHello", rpcmux.Person{Name: "Bob"}, rpcmux.Person{Name: "bob"})
+	m.Expect("RPCMethod.SayHello", rpcmux.Person{Name: "Anto"}, rpcmux.Person{Name: "second"})
+
+	for _, c := range []struct{ name, want string }{
+		{"Anto", "first"},
+		{"Anto", "second"},
+		{"Bob", "bob"},
+	} {
+		var reply rpcmux.Person
+		if err := m.Call("RPCMethod.SayHello", rpcmux.Person{Name: c.name}, &reply); err != nil {
+			t.Fatalf("Call(%s): %v", c.name, err)
+		}
+		if reply.Name != c.want {
+			t.Errorf("Call(%s) = %q, want %q", c.name, reply.Name, c.want)
+		}
+	}
+
+	var reply rpcmux.Person
+	if err := m.Call("RPCMethod.SayHello", rpcmux.Person{Name: "Anto"}, &reply); err == nil {
+		t.Error("Call matching only met expectations succeeded")
+	}
+	if err := m.Call("RPCMethod.Other", rpcmux.Person{Name: "Bob"}, &reply); err == nil {
+		t.Error("Call of another method succeeded")
+	}
+}
+
+func TestHandleFallback(t *testing.T) {
+	m := New()
+	m.Expect("RPCMethod.SayHello", rpcmux.Person{Name: "Anto"}, rpcmux.Person{Name: "expected"})
+	m.Handle("RPCMethod.SayHello", func(args, reply interface{}) error {
+		*reply.(*rpcmux.Person) = rpcmux.Person{Name: "handled " + args.(rpcmux.Person).Name}
+		return nil
+	})
+
+	for _, c := range []struct{ name, want string }{
+		{"Bob", "handled Bob"},
+		{"Anto", "expected"},
+		{"Anto", "handled Anto"},
+	} {
+		var reply rpcmux.Person
+		if err := m.Call("RPCMethod.SayHello", rpcmux.Person{Name: c.name}, &reply); err != nil {
+			t.Fatalf("Call(%s): %v", c.name, err)
+		}
+		if reply.Name != c.want {
+			t.Errorf("Call(%s) = %q, want %q", c.name, reply.Name, c.want)
+		}
+	}
+}
+
+type tags struct {
+	Names []string
+}
+
+type unencodable struct {
+	C chan int
+}
+
+func TestReplyCopiedThroughGob(t *testing.T) {
+	m := New()
+	output := tags{Names: []string{"a", "b"}}
+	m.Expect("Tags.Get", "x", output)
+	m.Expect("Tags.Get", "y", output)
+	m.Expect("Tags.Get", "z", unencodable{make(chan int)})
+
+	var reply tags
+	if err := m.Call("Tags.Get", "x", &reply); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	reply.Names[0] = "changed"
+	if output.Names[0] != "a" {
+		t.Error("reply shares memory with the expected output")
+	}
+
+	var wrong rpcmux.Person
+	if err := m.Call("Tags.Get", "y", &wrong); err == nil {
+		t.Error("Call with a reply of another type succeeded")
+	}
+	if err := m.Call("Tags.Get", "z", &reply); err == nil {
+		t.Error("Call with an output gob cannot encode succeeded")
+	}
+}
+
+func TestExpectationsWereMet(t *testing.T) {
+	m := New()
+	m.Expect("RPCMethod.SayHello", rpcmux.Person{Name: "Anto"}, rpcmux.Person{})
+	m.Expect("RPCMethod.SayHello", rpcmux.Person{Name: "Bob"}, rpcmux.Person{})
+
+	var reply rpcmux.Person
+	if err := m.Call("RPCMethod.SayHello", rpcmux.Person{Name: "Anto"}, &reply); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	err := m.ExpectationsWereMet()
+	if err == nil || !strings.Contains(err.Error(), "Bob") || strings.Contains(err.Error(), "Anto") {
+		t.Fatalf("ExpectationsWereMet = %v, want an error listing the call with Bob only", err)
+	}
+
+	if err := m.Call("RPCMethod.SayHello", rpcmux.Person{Name: "Bob"}, &reply); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if err := m.ExpectationsWereMet(); err != nil {
+		t.Errorf("ExpectationsWereMet once all calls were made = %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	m := New()
+	m.Handle("RPCMethod.SayHello", func(args, reply interface{}) error { return nil })
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	var reply rpcmux.Person
+	if err := m.Call("RPCMethod.SayHello", rpcmux.Person{}, &reply); !errors.Is(err, rpc.ErrShutdown) {
+		t.Errorf("Call after Close = %v, want rpc.ErrShutdown", err)
+	}
+	if err := m.Close(); !errors.Is(err, rpc.ErrShutdown) {
+		t.Errorf("second Close = %v, want rpc.ErrShutdown", err)
+	}
+}
@@ -0,0 +1,262 @@
+// Package mutex provides a mutual exclusion lock shared by processes connected over RPCDuplex.
+//
+// One end serves a LockTable; the other ends lock and unlock named mutexes in it with DistMutex.
+// A held lock is kept alive by its holder and expires after TTL if the holder dies, so a
+// crashed process cannot keep a lock forever. It is released at once if the connection of
+// the holder is lost.
+package mutex
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/rpc"
+	"sync"
+	"time"
+
+	rpcmux "github.com/atang152/test_duplex"
+)
+
+const (
+	// serviceName is the name the LockTable is registered under.
+	serviceName = "DistMutex"
+
+	// TTL is how long a lock outlives its last renewal by the holder.
+	TTL = 10 * time.Second
+
+	// retryInterval is how often Lock retries while the mutex is held by someone else.
+	retryInterval = 50 * time.Millisecond
+)
+
+// ErrNotLocked is returned by Unlock for a DistMutex that is not locked.
+var ErrNotLocked = errors.New("mutex: not locked")
+
+// LockRequest is the argument of the DistMutex service methods.
+type LockRequest struct {
+	Name  string
+	Owner string
+	TTL   time.Duration
+}
+
+// LockTable holds the state of every named mutex.
+type LockTable struct {
+	mu    sync.Mutex
+	locks map[string]lease
+}
+
+type lease struct {
+	owner  string
+	expiry time.Time
+	via    *lockService // the service of the duplex the lease was taken through
+}
+
+// NewLockTable returns a LockTable with every mutex unlocked.
+func NewLockTable() *LockTable {
+	return &LockTable{locks: make(map[string]lease)}
+}
+
+// Serve registers t on d so that the peer at the other end can use its mutexes.
+// The same LockTable is served on the duplex of every peer sharing the mutexes.
+// The leases taken through d are released once its connection is lost.
+func (t *LockTable) Serve(d *rpcmux.RPCDuplex) error {
+	s := &lockService{t}
+	if err := d.RegisterName(serviceName, s); err != nil {
+		return err
+	}
+	go func() {
+		<-d.Done()
+		t.drop(s)
+	}()
+	return nil
+}
+
+// drop releases the leases taken through s.
+func (t *LockTable) drop(s *lockService) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for name, l := range t.locks {
+		if l.via == s {
+			delete(t.locks, name)
+		}
+	}
+}
+
+// lockService is the RPC facade of a LockTable.
+type lockService struct {
+	t *LockTable
+}
+
+// TryLock takes the mutex for req.Owner if it is free or expired, and reports whether it did.
+func (s *lockService) TryLock(req LockRequest, acquired *bool) error {
+	s.t.mu.Lock()
+	defer s.t.mu.Unlock()
+
+	now := time.Now()
+	if l, ok := s.t.locks[req.Name]; ok && l.owner != req.Owner && now.Before(l.expiry) {
+		*acquired = false
+		return nil
+	}
+	s.t.locks[req.Name] = lease{owner: req.Owner, expiry: now.Add(req.TTL), via: s}
+	*acquired = true
+	return nil
+}
+
+// Renew extends the lease of req.Owner, and reports whether it still held the mutex.
+func (s *lockService) Renew(req LockRequest, held *bool) error {
+	s.t.mu.Lock()
+	defer s.t.mu.Unlock()
+
+	now := time.Now()
+	l, ok := s.t.locks[req.Name]
+	*held = ok && l.owner == req.Owner && now.Before(l.expiry)
+	if *held {
+		l.expiry = now.Add(req.TTL)
+		s.t.locks[req.Name] = l
+	}
+	return nil
+}
+
+// Unlock releases the mutex if it is held by req.Owner.
+func (s *lockService) Unlock(req LockRequest, _ *struct{}) error {
+	s.t.mu.Lock()
+	defer s.t.mu.Unlock()
+
+	l, ok := s.t.locks[req.Name]
+	if !ok || l.owner != req.Owner || time.Now().After(l.expiry) {
+		return ErrNotLocked
+	}
+	delete(s.t.locks, req.Name)
+	return nil
+}
+
+// DistMutex is a named mutex in the LockTable served at the other end of a RPCDuplex.
+// Unlike sync.Mutex, it is owned by the DistMutex value that locked it.
+type DistMutex struct {
+	d     *rpcmux.RPCDuplex
+	name  string
+	owner string
+
+	// sem holds a token from the start of Lock until Unlock, or until an abandoned TryLock
+	// call is answered, so that a single goroutine locks the DistMutex at a time.
+	sem chan struct{}
+
+	mu   sync.Mutex
+	stop chan struct{} // closed by Unlock to stop renewing, nil while unlocked
+	done chan struct{}
+}
+
+// New returns the DistMutex name of the LockTable served at the other end of d.
+func New(d *rpcmux.RPCDuplex, name string) *DistMutex {
+	var id [16]byte
+	rand.Read(id[:])
+	return &DistMutex{d: d, name: name, owner: hex.EncodeToString(id[:]), sem: make(chan struct{}, 1)}
+}
+
+// Lock waits until the mutex is acquired or ctx is done.
+// While locked, the lease is renewed in the background until Unlock.
+// As with sync.Mutex, Lock waits while the DistMutex is locked, by another goroutine or
+// by the calling one.
+func (m *DistMutex) Lock(ctx context.Context) error {
+	select {
+	case m.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	req := LockRequest{Name: m.name, Owner: m.owner, TTL: TTL}
+	for {
+		res := m.tryLock(req)
+		select {
+		case r := <-res:
+			if r.err != nil {
+				<-m.sem
+				return r.err
+			}
+			if r.acquired {
+				m.mu.Lock()
+				m.stop, m.done = make(chan struct{}), make(chan struct{})
+				go m.renew(req, m.stop, m.done)
+				m.mu.Unlock()
+				return nil
+			}
+		case <-ctx.Done():
+			go m.abandon(res)
+			return ctx.Err()
+		}
+
+		select {
+		case <-time.After(retryInterval):
+		case <-ctx.Done():
+			<-m.sem
+			return ctx.Err()
+		}
+	}
+}
+
+type tryLockResult struct {
+	acquired bool
+	err      error
+}
+
+// tryLock calls TryLock in the background, so that Lock can give up on it when ctx is done.
+func (m *DistMutex) tryLock(req LockRequest) <-chan tryLockResult {
+	res := make(chan tryLockResult, 1)
+	go func() {
+		var acquired bool
+		err := m.d.Call(serviceName+".TryLock", req, &acquired)
+		res <- tryLockResult{acquired, err}
+	}()
+	return res
+}
+
+// abandon waits for the answer to a TryLock call that Lock gave up on, and releases the
+// mutex if it was acquired nonetheless, rather than leave it held until its lease expires.
+func (m *DistMutex) abandon(res <-chan tryLockResult) {
+	if r := <-res; r.err == nil && r.acquired {
+		m.d.Call(serviceName+".Unlock", LockRequest{Name: m.name, Owner: m.owner}, &struct{}{})
+	}
+	<-m.sem
+}
+
+// Unlock releases the mutex. It returns ErrNotLocked if the mutex was not locked,
+// including when its lease expired because it could not be renewed.
+func (m *DistMutex) Unlock() error {
+	m.mu.Lock()
+	stop, done := m.stop, m.done
+	m.stop, m.done = nil, nil
+	m.mu.Unlock()
+
+	if stop == nil {
+		return ErrNotLocked
+	}
+	close(stop)
+	<-done
+
+	err := m.d.Call(serviceName+".Unlock", LockRequest{Name: m.name, Owner: m.owner}, &struct{}{})
+	<-m.sem
+	if err == rpc.ServerError(ErrNotLocked.Error()) {
+		return ErrNotLocked
+	}
+	return err
+}
+
+func (m *DistMutex) renew(req LockRequest, stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(req.TTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			var held bool
+			if err := m.d.Call(serviceName+".Renew", req, &held); err == nil && !held {
+				// The lease was lost; Unlock will report it.
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
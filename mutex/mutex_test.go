@@ -0,0 +1,122 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	rpcmux "github.com/atang152/test_duplex"
+	"github.com/atang152/test_duplex/internal/rpctest"
+)
+
+func TestLockUnlock(t *testing.T) {
+	table := NewLockTable()
+	m1 := New(rpctest.Serve(t, table.Serve), "m")
+	m2 := New(rpctest.Serve(t, table.Serve), "m")
+
+	if err := m1.Lock(context.Background()); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*retryInterval)
+	defer cancel()
+	if err := m2.Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Lock of a held mutex = %v, want context.DeadlineExceeded", err)
+	}
+
+	if err := m1.Unlock(); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if err := m2.Lock(context.Background()); err != nil {
+		t.Fatalf("Lock after Unlock: %v", err)
+	}
+	if err := m2.Unlock(); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if err := m2.Unlock(); err != ErrNotLocked {
+		t.Errorf("second Unlock = %v, want ErrNotLocked", err)
+	}
+}
+
+// TestConcurrentLock locks the same DistMutex from several goroutines at once: each of them
+// waits for its turn.
+func TestConcurrentLock(t *testing.T) {
+	m := New(rpctest.Serve(t, NewLockTable().Serve), "m")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var holders atomic.Int32
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.Lock(ctx); err != nil {
+				t.Errorf("Lock: %v", err)
+				return
+			}
+			if n := holders.Add(1); n != 1 {
+				t.Errorf("%d goroutines hold the mutex", n)
+			}
+			time.Sleep(time.Millisecond)
+			holders.Add(-1)
+			if err := m.Unlock(); err != nil {
+				t.Errorf("Unlock: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// slowLockService answers TryLock after a delay.
+type slowLockService struct {
+	*lockService
+	delay time.Duration
+}
+
+func (s slowLockService) TryLock(req LockRequest, acquired *bool) error {
+	time.Sleep(s.delay)
+	return s.lockService.TryLock(req, acquired)
+}
+
+// TestLockCancelledDuringTryLock gives up on Lock while its TryLock call is in flight: the
+// lease granted afterwards must be released rather than held until it expires.
+func TestLockCancelledDuringTryLock(t *testing.T) {
+	table := NewLockTable()
+	client := rpctest.Serve(t, func(d *rpcmux.RPCDuplex) error {
+		return d.RegisterName(serviceName, slowLockService{&lockService{table}, 50 * time.Millisecond})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := New(client, "m").Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Lock = %v, want context.DeadlineExceeded", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), TTL/2)
+	defer cancel()
+	if err := New(rpctest.Serve(t, table.Serve), "m").Lock(ctx); err != nil {
+		t.Fatalf("Lock after the other Lock gave up: %v", err)
+	}
+}
+
+// TestLockReleasedOnConnectionLoss closes the connection of the holder of a mutex: the
+// mutex must be free straight away rather than after TTL.
+func TestLockReleasedOnConnectionLoss(t *testing.T) {
+	table := NewLockTable()
+	holder := rpctest.Serve(t, table.Serve)
+	if err := New(holder, "m").Lock(context.Background()); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	holder.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), TTL/2)
+	defer cancel()
+	if err := New(rpctest.Serve(t, table.Serve), "m").Lock(ctx); err != nil {
+		t.Fatalf("Lock after the holder was disconnected: %v", err)
+	}
+}
@@ -0,0 +1,332 @@
+package rpcmux
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Frame tags, telling which side of the reading end a frame is for. Requests go from the
+// rpc.Client of one end to the rpc.Server of the other, and responses come back the other
+// way, so both ends tag the same way: no initiator has to be agreed on. Each end starts with
+// a hello frame carrying its ProtocolVersion.
+const (
+	tagHello    byte = 'H'
+	tagRequest  byte = 'Q'
+	tagResponse byte = 'R'
+)
+
+// frameHeaderLen is the length of the header of a frame: its tag and payload length.
+const frameHeaderLen = 5
+
+// maxFramePayload is the largest payload sent in one frame. Longer writes are split, and a
+// longer length read from the peer is rejected as a malformed frame.
+const maxFramePayload = 1 << 20
+
+// ErrBadFrame is the error of the calls of a duplex whose peer sent a frame with an unknown
+// tag or an oversized length. The connection is closed when it happens.
+var ErrBadFrame = errors.New("rpcmux: malformed frame")
+
+// demux owns the reading side of the connection of a RPCDuplex. A single goroutine reads
+// every frame and queues its payload for the rpc.Server or the rpc.Client, so that neither
+// can read a message meant for the other. Writers share one lock, so that frames are never
+// interleaved.
+type demux struct {
+	conn net.Conn
+	wmu  sync.Mutex
+	wbuf []byte // frame being written, reused across writes
+
+	failMu  sync.Mutex
+	failErr error // why run closed conn, set before closing it, or ErrHijacked
+
+	frameTimeout time.Duration // set by WithFrameHeaderTimeout, 0 for none
+
+	hijacked atomic.Bool   // set by hijack, under wmu
+	done     chan struct{} // closed once run returns
+	err      error         // error run returned with, set before done is closed
+	rest     []byte        // bytes read but not handled, when run returned for hijack
+
+	requests  *inbound // for the rpc.Server of this end
+	responses *inbound // for the rpc.Client of this end
+}
+
+func newDemux(conn net.Conn, frameTimeout time.Duration) *demux {
+	return &demux{
+		conn:         conn,
+		frameTimeout: frameTimeout,
+		done:         make(chan struct{}),
+		requests:     newInbound(),
+		responses:    newInbound(),
+	}
+}
+
+// run reads frames until the connection fails or is hijacked, then fails both inbound queues
+// with the error.
+func (m *demux) run() {
+	defer close(m.done)
+
+	rest, err := m.readFrames()
+	switch {
+	case err == ErrHijacked:
+		m.rest = rest
+		m.failMu.Lock()
+		m.failErr = err
+		m.failMu.Unlock()
+	case err == ErrBadFrame, err == ErrFrameTimeout, errors.Is(err, ErrVersionMismatch):
+		m.failMu.Lock()
+		m.failErr = err
+		m.failMu.Unlock()
+		m.conn.Close()
+	case errors.Is(err, net.ErrClosed):
+		// Closed by this end: report it as the end of the stream, which net/rpc
+		// turns into rpc.ErrShutdown for the calls still pending.
+		err = io.EOF
+	}
+	m.err = err
+	m.requests.close(err)
+	m.responses.close(err)
+}
+
+// readFrames reads frames until the connection fails. It also returns the bytes it read but did
+// not queue: those of the frame it was reading, and those buffered after it.
+func (m *demux) readFrames() ([]byte, error) {
+	r := bufio.NewReaderSize(m.conn, 64<<10)
+	var hdr [frameHeaderLen]byte
+	for hello := false; ; hello = true {
+		if m.frameTimeout > 0 {
+			// The timeout starts with the frame, as the connection may stay idle in between.
+			if _, err := r.Peek(1); err != nil {
+				return unread(r, nil), m.frameError(err)
+			}
+			if !m.setReadDeadline(time.Now().Add(m.frameTimeout)) {
+				return unread(r, nil), ErrHijacked
+			}
+		}
+		if k, err := io.ReadFull(r, hdr[:]); err != nil {
+			return unread(r, hdr[:k]), m.frameError(err)
+		}
+		n := binary.BigEndian.Uint32(hdr[1:])
+		if n > maxFramePayload {
+			return nil, ErrBadFrame
+		}
+		var in *inbound // nil for the hello frame
+		switch {
+		case !hello:
+			if hdr[0] != tagHello {
+				return nil, fmt.Errorf("%w: peer sent no version", ErrVersionMismatch)
+			}
+		case hdr[0] == tagRequest:
+			in = m.requests
+		case hdr[0] == tagResponse:
+			in = m.responses
+		default:
+			return nil, ErrBadFrame
+		}
+		if m.frameTimeout > 0 && !m.setReadDeadline(payloadDeadline(m.frameTimeout, n)) {
+			return unread(r, hdr[:]), ErrHijacked
+		}
+		payload := make([]byte, n)
+		if k, err := io.ReadFull(r, payload); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return unread(r, append(hdr[:], payload[:k]...)), m.frameError(err)
+		}
+		if m.frameTimeout > 0 && !m.setReadDeadline(time.Time{}) {
+			return unread(r, append(hdr[:], payload...)), ErrHijacked
+		}
+		if in == nil {
+			if err := checkHello(payload); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		in.push(payload)
+	}
+}
+
+// sendHello sends the hello frame ahead of any other frame. It does not wait for the peer to
+// read it, as the peer may not be reading yet: the other writers wait until it is written.
+func (m *demux) sendHello() {
+	m.wmu.Lock()
+	go func() {
+		defer m.wmu.Unlock()
+		m.writeLocked(tagHello, binary.BigEndian.AppendUint16(nil, ProtocolVersion))
+	}()
+}
+
+// checkHello checks the payload of the hello frame of the peer.
+func checkHello(payload []byte) error {
+	if len(payload) < 2 {
+		return ErrBadFrame
+	}
+	if v := binary.BigEndian.Uint16(payload); v != ProtocolVersion {
+		return fmt.Errorf("%w: peer speaks version %d, this end %d", ErrVersionMismatch, v, ProtocolVersion)
+	}
+	return nil
+}
+
+// failure returns the error that made run close or give up the connection, or nil.
+func (m *demux) failure() error {
+	m.failMu.Lock()
+	defer m.failMu.Unlock()
+	return m.failErr
+}
+
+// frameError returns the error to report for err, met while reading a frame.
+func (m *demux) frameError(err error) error {
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		return err
+	}
+	switch {
+	case m.hijacked.Load():
+		return ErrHijacked
+	case m.frameTimeout > 0:
+		return ErrFrameTimeout
+	}
+	return err
+}
+
+// setReadDeadline sets the read deadline of the connection for the next frame, unless the
+// connection is being hijacked. It reports whether it did.
+func (m *demux) setReadDeadline(t time.Time) bool {
+	m.conn.SetReadDeadline(t)
+	return !m.hijacked.Load()
+}
+
+// unread returns the bytes of the frame being read, followed by those read ahead by r.
+func unread(r *bufio.Reader, partial []byte) []byte {
+	buffered, _ := r.Peek(r.Buffered())
+	return append(append([]byte(nil), partial...), buffered...)
+}
+
+// write sends p in frames tagged with tag. Once the connection is closed because of a
+// malformed or late frame, it fails with ErrBadFrame or ErrFrameTimeout.
+func (m *demux) write(tag byte, p []byte) (int, error) {
+	m.wmu.Lock()
+	defer m.wmu.Unlock()
+	return m.writeLocked(tag, p)
+}
+
+func (m *demux) writeLocked(tag byte, p []byte) (int, error) {
+	if m.hijacked.Load() {
+		return 0, ErrHijacked
+	}
+	written := 0
+	for len(p) > 0 {
+		n := len(p)
+		if n > maxFramePayload {
+			n = maxFramePayload
+		}
+		m.wbuf = append(m.wbuf[:0], tag)
+		m.wbuf = binary.BigEndian.AppendUint32(m.wbuf, uint32(n))
+		m.wbuf = append(m.wbuf, p[:n]...)
+		if _, err := m.conn.Write(m.wbuf); err != nil {
+			if ferr := m.failure(); ferr != nil {
+				err = ferr
+			}
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// stream is the half of the connection used by the rpc.Client or the rpc.Server of an end:
+// it reads the frames queued for it and writes frames with its own tag.
+type stream struct {
+	m   *demux
+	in  *inbound
+	tag byte
+}
+
+// clientStream returns the stream carrying requests out and responses in.
+func (m *demux) clientStream() *stream {
+	return &stream{m: m, in: m.responses, tag: tagRequest}
+}
+
+// serverStream returns the stream carrying requests in and responses out.
+func (m *demux) serverStream() *stream {
+	return &stream{m: m, in: m.requests, tag: tagResponse}
+}
+
+func (s *stream) Read(p []byte) (int, error)  { return s.in.read(p) }
+func (s *stream) Write(p []byte) (int, error) { return s.m.write(s.tag, p) }
+
+// inbound queues the payloads of the frames read for one side of an end until they are read.
+// The queue is unbounded, so the reading goroutine never waits for a side that is not reading,
+// such as a rpc.Server that is not served yet.
+type inbound struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	chunks [][]byte
+	err    error // set once no more payloads will be queued
+}
+
+func newInbound() *inbound {
+	in := &inbound{}
+	in.cond = sync.NewCond(&in.mu)
+	return in
+}
+
+func (in *inbound) push(p []byte) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if in.err == nil && len(p) > 0 {
+		in.chunks = append(in.chunks, p)
+		in.cond.Signal()
+	}
+}
+
+// read reads queued bytes, waiting for some if there are none. Once the queue is closed,
+// the bytes queued before are still read, then err is returned.
+func (in *inbound) read(p []byte) (int, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	for len(in.chunks) == 0 {
+		if in.err != nil {
+			return 0, in.err
+		}
+		in.cond.Wait()
+	}
+	n := copy(p, in.chunks[0])
+	if in.chunks[0] = in.chunks[0][n:]; len(in.chunks[0]) == 0 {
+		in.chunks[0] = nil
+		in.chunks = in.chunks[1:]
+	}
+	return n, nil
+}
+
+// close makes the reads fail with err once the queued bytes are read.
+// Later pushes are dropped. Only the first err is kept.
+func (in *inbound) close(err error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if in.err == nil {
+		in.err = err
+		in.cond.Broadcast()
+	}
+}
+
+// discard drops the queued bytes and closes the queue with err.
+func (in *inbound) discard(err error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.chunks = nil
+	if in.err == nil {
+		in.err = err
+	}
+	in.cond.Broadcast()
+}
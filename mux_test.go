@@ -0,0 +1,52 @@
+package rpcmux
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+// slowConn is a net.Conn whose reads return one byte at a time, as a transport splitting
+// every frame across many reads would.
+type slowConn struct {
+	net.Conn
+}
+
+func (c slowConn) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return c.Conn.Read(p)
+}
+
+// helloFrame returns the hello frame an end of this version starts with.
+func helloFrame() []byte {
+	return []byte{tagHello, 0, 0, 0, 2, byte(ProtocolVersion >> 8), byte(ProtocolVersion)}
+}
+
+func TestDemuxSplitFrame(t *testing.T) {
+	connA, connB := net.Pipe()
+	a, b := serveBoth(t, slowConn{connA}, slowConn{connB})
+	callBothWays(t, a, b, 5, strings.Repeat("x", 100))
+}
+
+// TestMaxFrameSizeEnforcement sends a frame one byte longer than maxFramePayload: the calls
+// must fail with ErrBadFrame and the connection be closed.
+func TestMaxFrameSizeEnforcement(t *testing.T) {
+	connA, connB := net.Pipe()
+	d := NewRPCDuplex(connA)
+	defer d.Close()
+
+	hdr := binary.BigEndian.AppendUint32(append(helloFrame(), tagResponse), maxFramePayload+1)
+	go connB.Write(hdr)
+
+	var reply Person
+	if err := d.Call("RPCMethod.SayHello", Person{}, &reply); !errors.Is(err, ErrBadFrame) {
+		t.Errorf("Call = %v, want ErrBadFrame", err)
+	}
+	if _, err := connB.Write([]byte{0}); err == nil {
+		t.Error("connection still open after an oversized frame")
+	}
+}
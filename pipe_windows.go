@@ -0,0 +1,19 @@
+//go:build windows
+
+package rpcmux
+
+import "github.com/Microsoft/go-winio"
+
+// NewRPCDuplexNamedPipe connects to the Windows named pipe pipeName, such as `\\.\pipe\rpcmux`,
+// and returns the connection as a RPCDuplex.
+func NewRPCDuplexNamedPipe(pipeName string, opts ...Option) (*RPCDuplex, error) {
+	conn, err := winio.DialPipe(pipeName, nil)
+	if err != nil {
+		return nil, err
+	}
+	d, err := newRPCDuplex(conn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -0,0 +1,146 @@
+package rpcmux
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// proxyV2Signature starts every PROXY protocol v2 header.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// PROXY protocol v2 commands, address families and the stream transport.
+const (
+	proxyV2Local = 0x0
+	proxyV2Proxy = 0x1
+
+	proxyV2Unspec = 0x0
+	proxyV2Inet   = 0x1
+	proxyV2Inet6  = 0x2
+	proxyV2Unix   = 0x3
+
+	proxyV2Stream = 0x1
+)
+
+// proxyHeaderTimeout bounds the time WithPROXYProtocolV2 waits for the header, so that a peer
+// sending nothing cannot stall a DuplexListener.
+var proxyHeaderTimeout = 5 * time.Second
+
+// ErrBadPROXYHeader is reported when the connection does not start with a valid PROXY protocol v2 header.
+var ErrBadPROXYHeader = errors.New("rpcmux: bad PROXY protocol v2 header")
+
+// WithPROXYProtocolV2 reads a PROXY protocol v2 header from the connection before any RPC traffic,
+// as sent by load balancers such as HAProxy or AWS NLB. The client address carried by the header
+// is returned by ProxyAddr. The header must arrive within 5 seconds.
+func WithPROXYProtocolV2() Option {
+	timeout := proxyHeaderTimeout
+	return func(d *RPCDuplex) {
+		if d.err != nil {
+			return
+		}
+		d.Conn.SetReadDeadline(time.Now().Add(timeout))
+		addr, err := readPROXYHeader(d.Conn)
+		d.Conn.SetReadDeadline(time.Time{})
+		if err != nil {
+			d.fail(err)
+			return
+		}
+		d.proxyAddr = addr
+	}
+}
+
+// WithSendPROXYProtocolV2 writes a PROXY protocol v2 header describing the connection before
+// any RPC traffic, for peers created WithPROXYProtocolV2.
+func WithSendPROXYProtocolV2() Option {
+	return func(d *RPCDuplex) {
+		if d.err != nil {
+			return
+		}
+		if _, err := d.Conn.Write(proxyHeader(d.Conn.LocalAddr(), d.Conn.RemoteAddr())); err != nil {
+			d.fail(err)
+		}
+	}
+}
+
+// ProxyAddr returns the client address read by WithPROXYProtocolV2.
+// It returns nil if no header was read, or if the header did not carry an address.
+func (d *RPCDuplex) ProxyAddr() net.Addr {
+	return d.proxyAddr
+}
+
+// readPROXYHeader reads a PROXY protocol v2 header from r and returns the source address it carries.
+// It reads exactly the bytes of the header, leaving the rest of the stream untouched.
+func readPROXYHeader(r io.Reader) (net.Addr, error) {
+	var hdr [16]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(hdr[:12], proxyV2Signature) || hdr[12]>>4 != 2 {
+		return nil, ErrBadPROXYHeader
+	}
+	body := make([]byte, binary.BigEndian.Uint16(hdr[14:]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	switch hdr[12] & 0xF {
+	case proxyV2Local:
+		// Health checks from the proxy itself: the connection endpoints are the real ones.
+		return nil, nil
+	case proxyV2Proxy:
+	default:
+		return nil, fmt.Errorf("%w: unknown command %#x", ErrBadPROXYHeader, hdr[12]&0xF)
+	}
+
+	family, transport := hdr[13]>>4, hdr[13]&0xF
+	switch {
+	case family == proxyV2Inet && len(body) >= 12:
+		return proxyIPAddr(transport, net.IP(body[0:4]), binary.BigEndian.Uint16(body[8:])), nil
+	case family == proxyV2Inet6 && len(body) >= 36:
+		return proxyIPAddr(transport, net.IP(body[0:16]), binary.BigEndian.Uint16(body[32:])), nil
+	case family == proxyV2Unix && len(body) >= 216:
+		name, _, _ := bytes.Cut(body[0:108], []byte{0})
+		return &net.UnixAddr{Name: string(name), Net: "unix"}, nil
+	case family == proxyV2Unspec:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%w: address family %#x with %d address bytes", ErrBadPROXYHeader, family, len(body))
+}
+
+func proxyIPAddr(transport byte, ip net.IP, port uint16) net.Addr {
+	ip = append(net.IP(nil), ip...)
+	if transport == proxyV2Stream {
+		return &net.TCPAddr{IP: ip, Port: int(port)}
+	}
+	return &net.UDPAddr{IP: ip, Port: int(port)}
+}
+
+// proxyHeader builds a PROXY protocol v2 header for a connection from src to dst.
+// Addresses other than TCP ones are sent as a LOCAL header without addresses.
+func proxyHeader(src, dst net.Addr) []byte {
+	hdr := append([]byte(nil), proxyV2Signature...)
+
+	s, sok := src.(*net.TCPAddr)
+	t, tok := dst.(*net.TCPAddr)
+	if !sok || !tok {
+		return append(hdr, 2<<4|proxyV2Local, proxyV2Unspec, 0, 0)
+	}
+
+	var addrs []byte
+	if s4, t4 := s.IP.To4(), t.IP.To4(); s4 != nil && t4 != nil {
+		hdr = append(hdr, 2<<4|proxyV2Proxy, proxyV2Inet<<4|proxyV2Stream)
+		addrs = append(append(addrs, s4...), t4...)
+	} else {
+		hdr = append(hdr, 2<<4|proxyV2Proxy, proxyV2Inet6<<4|proxyV2Stream)
+		addrs = append(append(addrs, s.IP.To16()...), t.IP.To16()...)
+	}
+	addrs = binary.BigEndian.AppendUint16(addrs, uint16(s.Port))
+	addrs = binary.BigEndian.AppendUint16(addrs, uint16(t.Port))
+
+	hdr = binary.BigEndian.AppendUint16(hdr, uint16(len(addrs)))
+	return append(hdr, addrs...)
+}
@@ -0,0 +1,141 @@
+package rpcmux
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func listenTCP(t *testing.T, opts ...Option) *DuplexListener {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dl := ListenRPCDuplex(l, opts...)
+	t.Cleanup(func() { dl.Close() })
+	return dl
+}
+
+func TestPROXYProtocolV2(t *testing.T) {
+	dl := listenTCP(t, WithPROXYProtocolV2())
+
+	conn, err := net.Dial("tcp", dl.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewRPCDuplex(conn, WithSendPROXYProtocolV2())
+	defer client.Close()
+
+	server, err := dl.Accept()
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	defer server.Close()
+	if got, want := server.ProxyAddr().String(), conn.LocalAddr().String(); got != want {
+		t.Errorf("ProxyAddr = %s, want %s", got, want)
+	}
+
+	server.Register(new(RPCMethod))
+	go server.Serve()
+	var reply Person
+	if err := client.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply); err != nil {
+		t.Errorf("Call after the PROXY header: %v", err)
+	}
+}
+
+// TestPROXYProtocolV2SkipsBadClients checks that a client sending nothing and a client
+// sending garbage do not keep Accept from returning the next good client.
+func TestPROXYProtocolV2SkipsBadClients(t *testing.T) {
+	defer func(timeout time.Duration) { proxyHeaderTimeout = timeout }(proxyHeaderTimeout)
+	proxyHeaderTimeout = 50 * time.Millisecond
+
+	dl := listenTCP(t, WithPROXYProtocolV2())
+	dial := func() net.Conn {
+		conn, err := net.Dial("tcp", dl.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	dial()
+	dial().Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+	good := NewRPCDuplex(dial(), WithSendPROXYProtocolV2())
+	defer good.Close()
+
+	accepted := make(chan *RPCDuplex, 1)
+	go func() {
+		d, err := dl.Accept()
+		if err != nil {
+			t.Errorf("Accept: %v", err)
+		}
+		accepted <- d
+	}()
+	select {
+	case d := <-accepted:
+		if d == nil {
+			return
+		}
+		defer d.Close()
+		if d.ProxyAddr() == nil {
+			t.Error("accepted a connection without PROXY address")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Accept blocked on bad clients")
+	}
+}
+
+// TestPROXYProtocolV2SilentClients checks that clients sending nothing do not delay the
+// next good client by the header timeout each.
+func TestPROXYProtocolV2SilentClients(t *testing.T) {
+	dl := listenTCP(t, WithPROXYProtocolV2())
+	for i := 0; i < 3; i++ {
+		conn, err := net.Dial("tcp", dl.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+	}
+	conn, err := net.Dial("tcp", dl.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := NewRPCDuplex(conn, WithSendPROXYProtocolV2())
+	defer good.Close()
+
+	accepted := make(chan *RPCDuplex, 1)
+	go func() {
+		d, _ := dl.Accept()
+		accepted <- d
+	}()
+	select {
+	case d := <-accepted:
+		if d == nil {
+			t.Fatal("Accept failed")
+		}
+		d.Close()
+	case <-time.After(proxyHeaderTimeout / 2):
+		t.Fatal("Accept waited for the headers of silent clients")
+	}
+}
+
+func TestDuplexListenerClose(t *testing.T) {
+	dl := listenTCP(t)
+	errc := make(chan error, 1)
+	go func() {
+		_, err := dl.Accept()
+		errc <- err
+	}()
+	dl.Close()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Errorf("Accept after Close = %v, want net.ErrClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Accept blocked after Close")
+	}
+}
@@ -0,0 +1,244 @@
+// Package pubsub provides topic based publish/subscribe between processes connected over RPCDuplex.
+//
+// A Broker runs on the serving end and fans messages out to every subscriber of a topic, each
+// subscriber being fed by its own goroutine. Processes at the other ends of the served duplexes
+// publish and subscribe with a Client, which receives its messages by polling the broker with
+// RPCDuplex.Call.
+//
+// Messages are sent with encoding/gob as interface values, so their concrete types must be
+// registered with gob.Register on every process, unless they are basic types such as string.
+package pubsub
+
+import (
+	"errors"
+	"net/rpc"
+	"sync"
+	"time"
+
+	rpcmux "github.com/atang152/test_duplex"
+	"github.com/atang152/test_duplex/internal/poll"
+)
+
+// serviceName is the name the Broker is registered under.
+const serviceName = "PubSub"
+
+// ErrUnknownSubscription is returned for a subscription that was cancelled or dropped.
+var ErrUnknownSubscription = errors.New("pubsub: unknown subscription")
+
+// Subscription is a subscription to a topic.
+type Subscription interface {
+	// Unsubscribe stops the delivery of messages to the subscription's handler.
+	Unsubscribe() error
+}
+
+// PublishRequest is the argument of PubSub.Publish.
+type PublishRequest struct {
+	Topic string
+	Msg   interface{}
+}
+
+// Broker delivers the messages published on a topic to its subscribers.
+type Broker struct {
+	mu     sync.Mutex
+	topics map[string]map[uint64]*subscriber
+	subs   map[uint64]*subscriber
+	nextID uint64
+}
+
+// NewBroker returns a Broker served on d. It can be served on more duplexes with Serve.
+func NewBroker(d *rpcmux.RPCDuplex) (*Broker, error) {
+	b := &Broker{
+		topics: make(map[string]map[uint64]*subscriber),
+		subs:   make(map[uint64]*subscriber),
+	}
+	if err := b.Serve(d); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Serve registers b on d so that the peer at the other end can publish and subscribe.
+func (b *Broker) Serve(d *rpcmux.RPCDuplex) error {
+	return d.RegisterName(serviceName, &brokerService{b})
+}
+
+// Publish delivers msg to the subscribers of topic. It does not wait for the handlers.
+func (b *Broker) Publish(topic string, msg interface{}) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, s := range b.topics[topic] {
+		s.queue.Push(msg)
+	}
+	return nil
+}
+
+// Subscribe calls handler with every message published on topic, in the order they were published,
+// from a goroutine dedicated to the subscription.
+func (b *Broker) Subscribe(topic string, handler func(interface{})) (Subscription, error) {
+	s := b.add(topic, false)
+	go func() {
+		for {
+			msgs, ok := s.queue.Pop(nil)
+			if !ok {
+				return
+			}
+			for _, msg := range msgs {
+				handler(msg)
+			}
+		}
+	}()
+	return &localSubscription{b: b, id: s.id}, nil
+}
+
+// add creates a subscription to topic. A remote one is dropped once its client stops polling.
+func (b *Broker) add(topic string, remote bool) *subscriber {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	s := &subscriber{id: b.nextID, topic: topic}
+	var onStale func()
+	if remote {
+		onStale = func() { b.remove(s.id) }
+	}
+	s.queue = poll.NewQueue[interface{}](onStale)
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[uint64]*subscriber)
+	}
+	b.topics[topic][s.id] = s
+	b.subs[s.id] = s
+	return s
+}
+
+func (b *Broker) remove(id uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subs[id] == nil {
+		return ErrUnknownSubscription
+	}
+	b.removeLocked(id)
+	return nil
+}
+
+func (b *Broker) removeLocked(id uint64) {
+	s := b.subs[id]
+	delete(b.subs, id)
+	delete(b.topics[s.topic], id)
+	if len(b.topics[s.topic]) == 0 {
+		delete(b.topics, s.topic)
+	}
+	s.queue.Close()
+}
+
+func (b *Broker) lookup(id uint64) *subscriber {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.subs[id]
+}
+
+// subscriber is one subscription, queueing its messages until they are delivered.
+type subscriber struct {
+	id    uint64
+	topic string
+	queue *poll.Queue[interface{}]
+}
+
+type localSubscription struct {
+	b  *Broker
+	id uint64
+}
+
+func (s *localSubscription) Unsubscribe() error {
+	return s.b.remove(s.id)
+}
+
+// brokerService is the RPC facade of a Broker.
+type brokerService struct {
+	b *Broker
+}
+
+// Publish publishes req.Msg on req.Topic.
+func (s *brokerService) Publish(req PublishRequest, _ *struct{}) error {
+	return s.b.Publish(req.Topic, req.Msg)
+}
+
+// Subscribe creates a subscription to topic, replying with its ID to poll with Next.
+func (s *brokerService) Subscribe(topic string, id *uint64) error {
+	*id = s.b.add(topic, true).id
+	return nil
+}
+
+// Next waits for the messages of the subscription id.
+// It replies with no messages if none were published within poll.Timeout.
+func (s *brokerService) Next(id uint64, msgs *[]interface{}) error {
+	sub := s.b.lookup(id)
+	if sub == nil {
+		return ErrUnknownSubscription
+	}
+	timer := time.NewTimer(poll.Timeout)
+	defer timer.Stop()
+
+	queued, ok := sub.queue.Pop(timer.C)
+	if !ok {
+		return ErrUnknownSubscription
+	}
+	*msgs = queued
+	return nil
+}
+
+// Unsubscribe cancels the subscription id.
+func (s *brokerService) Unsubscribe(id uint64, _ *struct{}) error {
+	return s.b.remove(id)
+}
+
+// Client publishes and subscribes through the Broker served at the other end of a RPCDuplex.
+type Client struct {
+	d *rpcmux.RPCDuplex
+}
+
+// NewClient returns a Client of the Broker served at the other end of d.
+func NewClient(d *rpcmux.RPCDuplex) *Client {
+	return &Client{d: d}
+}
+
+// Publish publishes msg on topic.
+func (c *Client) Publish(topic string, msg interface{}) error {
+	return c.d.Call(serviceName+".Publish", PublishRequest{Topic: topic, Msg: msg}, &struct{}{})
+}
+
+// Subscribe calls handler with every message published on topic, in the order they were published,
+// from a goroutine polling the broker. Delivery stops when the subscription is cancelled or the
+// broker cannot be reached.
+func (c *Client) Subscribe(topic string, handler func(interface{})) (Subscription, error) {
+	var id uint64
+	if err := c.d.Call(serviceName+".Subscribe", topic, &id); err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			var msgs []interface{}
+			if err := c.d.Call(serviceName+".Next", id, &msgs); err != nil {
+				return
+			}
+			for _, msg := range msgs {
+				handler(msg)
+			}
+		}
+	}()
+	return &remoteSubscription{c: c, id: id}, nil
+}
+
+type remoteSubscription struct {
+	c  *Client
+	id uint64
+}
+
+func (s *remoteSubscription) Unsubscribe() error {
+	err := s.c.d.Call(serviceName+".Unsubscribe", s.id, &struct{}{})
+	if err == rpc.ServerError(ErrUnknownSubscription.Error()) {
+		return ErrUnknownSubscription
+	}
+	return err
+}
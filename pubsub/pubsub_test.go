@@ -0,0 +1,68 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	rpcmux "github.com/atang152/test_duplex"
+	"github.com/atang152/test_duplex/internal/rpctest"
+)
+
+// newBroker returns a Broker and a Client of it, connected over net.Pipe.
+func newBroker(t *testing.T) (*Broker, *Client) {
+	t.Helper()
+	var b *Broker
+	client := rpctest.Serve(t, func(d *rpcmux.RPCDuplex) (err error) {
+		b, err = NewBroker(d)
+		return err
+	})
+	return b, NewClient(client)
+}
+
+func receive(t *testing.T, ch <-chan interface{}) interface{} {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+		return nil
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	b, c := newBroker(t)
+
+	local, remote := make(chan interface{}, 2), make(chan interface{}, 2)
+	ls, err := b.Subscribe("news", func(msg interface{}) { local <- msg })
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	rs, err := c.Subscribe("news", func(msg interface{}) { remote <- msg })
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	if err := c.Publish("news", "hello"); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	b.Publish("news", "world")
+	for _, ch := range []chan interface{}{local, remote} {
+		if msg := receive(t, ch); msg != "hello" {
+			t.Errorf("first message = %v, want hello", msg)
+		}
+		if msg := receive(t, ch); msg != "world" {
+			t.Errorf("second message = %v, want world", msg)
+		}
+	}
+
+	if err := rs.Unsubscribe(); err != nil {
+		t.Errorf("Unsubscribe: %v", err)
+	}
+	if err := rs.Unsubscribe(); err != ErrUnknownSubscription {
+		t.Errorf("second Unsubscribe = %v, want ErrUnknownSubscription", err)
+	}
+	if err := ls.Unsubscribe(); err != nil {
+		t.Errorf("Unsubscribe: %v", err)
+	}
+}
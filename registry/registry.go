@@ -0,0 +1,126 @@
+// Package registry provides service discovery between processes connected over RPCDuplex.
+//
+// One end serves a Server holding the addresses registered under each service name; the
+// other ends register their own addresses and look up those of others with a Registry.
+// No external system such as etcd or Consul is needed.
+package registry
+
+import (
+	"errors"
+	"net/rpc"
+	"sync"
+
+	rpcmux "github.com/atang152/test_duplex"
+)
+
+// serviceName is the name the Server is registered under.
+const serviceName = "Registry"
+
+// ErrNotFound is returned by Lookup when no address is registered under the name.
+var ErrNotFound = errors.New("registry: service not found")
+
+// Entry is the argument of Registry.Register and Registry.Deregister.
+type Entry struct {
+	Name string
+	Addr string
+}
+
+// Server holds the addresses registered under each service name.
+type Server struct {
+	mu    sync.RWMutex
+	addrs map[string][]string
+}
+
+// NewServer returns an empty Server.
+func NewServer() *Server {
+	return &Server{addrs: make(map[string][]string)}
+}
+
+// Serve registers s on d so that the peer at the other end can use the registry.
+// The same Server is served on the duplex of every peer sharing the registry.
+func (s *Server) Serve(d *rpcmux.RPCDuplex) error {
+	return d.RegisterName(serviceName, &registryService{s})
+}
+
+// registryService is the RPC facade of a Server.
+type registryService struct {
+	s *Server
+}
+
+// Register adds e.Addr to the addresses of e.Name. Registering an address twice has no effect.
+func (r *registryService) Register(e Entry, _ *struct{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, addr := range r.s.addrs[e.Name] {
+		if addr == e.Addr {
+			return nil
+		}
+	}
+	r.s.addrs[e.Name] = append(r.s.addrs[e.Name], e.Addr)
+	return nil
+}
+
+// Deregister removes e.Addr from the addresses of e.Name.
+func (r *registryService) Deregister(e Entry, _ *struct{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	addrs := r.s.addrs[e.Name]
+	for i, addr := range addrs {
+		if addr == e.Addr {
+			addrs = append(addrs[:i:i], addrs[i+1:]...)
+			break
+		}
+	}
+	if len(addrs) == 0 {
+		delete(r.s.addrs, e.Name)
+		return nil
+	}
+	r.s.addrs[e.Name] = addrs
+	return nil
+}
+
+// Lookup replies with the addresses registered under name, in the order they were registered.
+func (r *registryService) Lookup(name string, addrs *[]string) error {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if len(r.s.addrs[name]) == 0 {
+		return ErrNotFound
+	}
+	*addrs = append([]string(nil), r.s.addrs[name]...)
+	return nil
+}
+
+// Registry is a client of the Server served at the other end of a RPCDuplex.
+type Registry struct {
+	d *rpcmux.RPCDuplex
+}
+
+// New returns a Registry using the Server served at the other end of d.
+func New(d *rpcmux.RPCDuplex) *Registry {
+	return &Registry{d: d}
+}
+
+// Register announces that the service name can be reached at addr.
+func (r *Registry) Register(name string, addr string) error {
+	return r.d.Call(serviceName+".Register", Entry{Name: name, Addr: addr}, &struct{}{})
+}
+
+// Deregister withdraws addr from the addresses of the service name.
+func (r *Registry) Deregister(name string, addr string) error {
+	return r.d.Call(serviceName+".Deregister", Entry{Name: name, Addr: addr}, &struct{}{})
+}
+
+// Lookup returns the addresses of the service name, or ErrNotFound if there are none.
+func (r *Registry) Lookup(name string) ([]string, error) {
+	var addrs []string
+	if err := r.d.Call(serviceName+".Lookup", name, &addrs); err != nil {
+		if err == rpc.ServerError(ErrNotFound.Error()) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return addrs, nil
+}
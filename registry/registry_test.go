@@ -0,0 +1,32 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/atang152/test_duplex/internal/rpctest"
+)
+
+func TestRegisterLookup(t *testing.T) {
+	s := NewServer()
+	r1, r2 := New(rpctest.Serve(t, s.Serve)), New(rpctest.Serve(t, s.Serve))
+
+	if err := r1.Register("api", "10.0.0.1:80"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := r2.Register("api", "10.0.0.2:80"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	r2.Register("api", "10.0.0.2:80")
+
+	addrs, err := r1.Lookup("api")
+	if want := []string{"10.0.0.1:80", "10.0.0.2:80"}; err != nil || !reflect.DeepEqual(addrs, want) {
+		t.Fatalf("Lookup = %v, %v, want %v", addrs, err, want)
+	}
+
+	r1.Deregister("api", "10.0.0.1:80")
+	r2.Deregister("api", "10.0.0.2:80")
+	if _, err := r1.Lookup("api"); err != ErrNotFound {
+		t.Errorf("Lookup after Deregister = %v, want ErrNotFound", err)
+	}
+}
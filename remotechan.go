@@ -0,0 +1,110 @@
+package rpcmux
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"sync"
+)
+
+// RemoteChanMessage is the argument of the reserved "_rpcmux.ChanSend" method, carrying a
+// gob-encoded value sent on the RemoteChan Name.
+type RemoteChanMessage struct {
+	Name  string
+	Value []byte
+}
+
+// RemoteChan gives channel semantics over a RPCDuplex: the values sent on a RemoteChan at one
+// end are received, in order, from the RemoteChan of the same name at the other end. Both ends
+// may send and receive. The channel is buffered without limit: Send returns once the peer has
+// queued the value, whether or not it is receiving.
+//
+// Values are sent with encoding/gob, so T must be a type gob can encode.
+type RemoteChan[T any] struct {
+	d    *RPCDuplex
+	name string
+}
+
+// NewRemoteChan returns the RemoteChan name of d.
+func NewRemoteChan[T any](d *RPCDuplex, name string) *RemoteChan[T] {
+	return &RemoteChan[T]{d: d, name: name}
+}
+
+// Send sends v to the RemoteChan of the same name at the other end of the duplex.
+func (c *RemoteChan[T]) Send(ctx context.Context, v T) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	return c.d.call(ctx, introspectionServiceName+".ChanSend", RemoteChanMessage{Name: c.name, Value: buf.Bytes()}, &struct{}{})
+}
+
+// Recv waits for the next value sent by the peer, or until ctx is done.
+func (c *RemoteChan[T]) Recv(ctx context.Context) (T, error) {
+	var v T
+	b, err := c.d.chanQueue(c.name).pop(ctx)
+	if err != nil {
+		return v, err
+	}
+	err = gob.NewDecoder(bytes.NewReader(b)).Decode(&v)
+	return v, err
+}
+
+// chanQueue returns the queue of the values received for the RemoteChan name.
+func (d *RPCDuplex) chanQueue(name string) *chanQueue {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.chans == nil {
+		d.chans = make(map[string]*chanQueue)
+	}
+	q := d.chans[name]
+	if q == nil {
+		q = &chanQueue{ready: make(chan struct{})}
+		d.chans[name] = q
+	}
+	return q
+}
+
+// chanQueue queues the values received for a RemoteChan until they are received.
+type chanQueue struct {
+	mu     sync.Mutex
+	values [][]byte
+	ready  chan struct{} // closed, and replaced, when a value is queued
+}
+
+func (q *chanQueue) push(b []byte) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.values = append(q.values, b)
+	close(q.ready)
+	q.ready = make(chan struct{})
+}
+
+func (q *chanQueue) pop(ctx context.Context) ([]byte, error) {
+	for {
+		q.mu.Lock()
+		if len(q.values) > 0 {
+			b := q.values[0]
+			q.values[0] = nil
+			q.values = q.values[1:]
+			q.mu.Unlock()
+			return b, nil
+		}
+		ready := q.ready
+		q.mu.Unlock()
+
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// ChanSend queues a value sent by the peer on a RemoteChan.
+func (s *introspection) ChanSend(msg RemoteChanMessage, _ *struct{}) error {
+	s.d.chanQueue(msg.Name).push(msg.Value)
+	return nil
+}
@@ -0,0 +1,53 @@
+package rpcmux
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRemoteChan(t *testing.T) {
+	a, b := newPipe(t)
+	ctx := context.Background()
+	ca, cb := NewRemoteChan[Person](a, "people"), NewRemoteChan[Person](b, "people")
+
+	for _, name := range []string{"one", "two"} {
+		if err := ca.Send(ctx, Person{Name: name}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	for _, want := range []string{"one", "two"} {
+		if v, err := cb.Recv(ctx); err != nil || v.Name != want {
+			t.Fatalf("Recv = %q, %v, want %q, nil", v.Name, err, want)
+		}
+	}
+
+	// The other way, with the receiver waiting first.
+	got := make(chan string, 1)
+	go func() {
+		v, err := ca.Recv(ctx)
+		if err != nil {
+			t.Errorf("Recv: %v", err)
+		}
+		got <- v.Name
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := cb.Send(ctx, Person{Name: "back"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if name := <-got; name != "back" {
+		t.Errorf("Recv = %q, want %q", name, "back")
+	}
+}
+
+func TestRemoteChanRecvContext(t *testing.T) {
+	a, b := newPipe(t)
+	NewRemoteChan[Person](a, "other").Send(context.Background(), Person{Name: "elsewhere"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := NewRemoteChan[Person](b, "people").Recv(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Recv with nothing sent = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,45 @@
+package rpcmux
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"time"
+)
+
+// WithWriteRetryPolicy retries writes to the underlying connection that fail with syscall.EAGAIN
+// (EWOULDBLOCK), as returned by transports over non-blocking sockets. A write is attempted
+// at most maxAttempts times, waiting delay between attempts; bytes already written are not resent.
+func WithWriteRetryPolicy(maxAttempts int, delay time.Duration) Option {
+	return func(d *RPCDuplex) {
+		if maxAttempts > 1 {
+			d.wrap(func(conn net.Conn) net.Conn {
+				return &retryConn{Conn: conn, maxAttempts: maxAttempts, delay: delay}
+			})
+		}
+	}
+}
+
+// retryConn is a net.Conn whose writes are retried on EAGAIN.
+type retryConn struct {
+	net.Conn
+	maxAttempts int
+	delay       time.Duration
+}
+
+func (c *retryConn) Write(p []byte) (int, error) {
+	written := 0
+	for attempt := 1; ; attempt++ {
+		n, err := c.Conn.Write(p[written:])
+		written += n
+		if err == nil || !errors.Is(err, syscall.EAGAIN) || attempt == c.maxAttempts {
+			return written, err
+		}
+		time.Sleep(c.delay)
+	}
+}
+
+// NetConn returns the wrapped connection.
+func (c *retryConn) NetConn() net.Conn {
+	return c.Conn
+}
@@ -0,0 +1,53 @@
+package rpcmux
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+)
+
+// eagainConn is a net.Conn whose writes fail with syscall.EAGAIN a given number of times
+// before succeeding. Only Write is implemented.
+type eagainConn struct {
+	net.Conn
+	fails int
+	buf   bytes.Buffer
+}
+
+func (c *eagainConn) Write(p []byte) (int, error) {
+	if c.fails > 0 {
+		c.fails--
+		return 0, syscall.EAGAIN
+	}
+	return c.buf.Write(p)
+}
+
+func TestWriteRetryPolicy(t *testing.T) {
+	conn := &eagainConn{fails: 2}
+	d := &RPCDuplex{Conn: conn}
+	WithWriteRetryPolicy(3, 0)(d)
+	d.applyWrappers()
+
+	if n, err := d.Conn.Write([]byte("hello")); err != nil || n != 5 {
+		t.Fatalf("Write = %d, %v, want 5, nil", n, err)
+	}
+	if got := conn.buf.String(); got != "hello" {
+		t.Errorf("written %q, want %q", got, "hello")
+	}
+}
+
+func TestWriteRetryPolicyGivesUp(t *testing.T) {
+	conn := &eagainConn{fails: 2}
+	d := &RPCDuplex{Conn: conn}
+	WithWriteRetryPolicy(2, 0)(d)
+	d.applyWrappers()
+
+	if _, err := d.Conn.Write([]byte("hello")); !errors.Is(err, syscall.EAGAIN) {
+		t.Errorf("Write = %v, want syscall.EAGAIN", err)
+	}
+	if conn.buf.Len() != 0 {
+		t.Errorf("written %q, want nothing", conn.buf.String())
+	}
+}
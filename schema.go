@@ -0,0 +1,76 @@
+package rpcmux
+
+import (
+	"errors"
+	"fmt"
+	"net/rpc"
+)
+
+// SchemaEncoding identifies the format of a schema registered with RegisterSchema.
+type SchemaEncoding int
+
+// Supported schema encodings.
+const (
+	JSONSchema SchemaEncoding = iota + 1 // a JSON Schema document
+	ProtoDesc                            // a serialised protobuf FileDescriptorSet
+	AvroSchema                           // an Avro schema in its JSON form
+)
+
+// String returns the name of the encoding.
+func (e SchemaEncoding) String() string {
+	switch e {
+	case JSONSchema:
+		return "JSON_SCHEMA"
+	case ProtoDesc:
+		return "PROTO_DESC"
+	case AvroSchema:
+		return "AVRO"
+	}
+	return fmt.Sprintf("SchemaEncoding(%d)", int(e))
+}
+
+// ErrNoSchema is returned by GetSchema when the peer has no schema registered for the method.
+var ErrNoSchema = errors.New("rpcmux: no schema registered for method")
+
+// MethodSchema is the reply of the reserved "_rpcmux.GetSchema" method.
+type MethodSchema struct {
+	Schema   []byte
+	Encoding SchemaEncoding
+}
+
+// RegisterSchema publishes the schema describing method, a "Service.Method" of this end,
+// so that the peer can retrieve it with GetSchema. Registering again replaces the schema.
+func (d *RPCDuplex) RegisterSchema(method string, schema []byte, encoding SchemaEncoding) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.schemas == nil {
+		d.schemas = make(map[string]MethodSchema)
+	}
+	d.schemas[method] = MethodSchema{Schema: append([]byte(nil), schema...), Encoding: encoding}
+}
+
+// GetSchema asks the peer for the schema it registered for method.
+func (d *RPCDuplex) GetSchema(method string) ([]byte, SchemaEncoding, error) {
+	var reply MethodSchema
+	if err := d.Call(introspectionServiceName+".GetSchema", method, &reply); err != nil {
+		if err == rpc.ServerError(ErrNoSchema.Error()) {
+			return nil, 0, ErrNoSchema
+		}
+		return nil, 0, err
+	}
+	return reply.Schema, reply.Encoding, nil
+}
+
+// GetSchema replies with the schema registered for method.
+func (s *introspection) GetSchema(method string, reply *MethodSchema) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	schema, ok := s.d.schemas[method]
+	if !ok {
+		return ErrNoSchema
+	}
+	*reply = schema
+	return nil
+}
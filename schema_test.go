@@ -0,0 +1,23 @@
+package rpcmux
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGetSchema(t *testing.T) {
+	a, b := newPipe(t)
+	schema := []byte(`{"type":"object","properties":{"Name":{"type":"string"}}}`)
+	b.RegisterSchema("RPCMethod.SayHello", schema, JSONSchema)
+
+	got, encoding, err := a.GetSchema("RPCMethod.SayHello")
+	if err != nil {
+		t.Fatalf("GetSchema: %v", err)
+	}
+	if !bytes.Equal(got, schema) || encoding != JSONSchema {
+		t.Errorf("GetSchema = %s, %v, want %s, %v", got, encoding, schema, JSONSchema)
+	}
+	if _, _, err := a.GetSchema("RPCMethod.Other"); err != ErrNoSchema {
+		t.Errorf("GetSchema of a method without schema = %v, want ErrNoSchema", err)
+	}
+}
@@ -0,0 +1,87 @@
+package rpcmux
+
+import (
+	"context"
+	"io"
+	"net/rpc"
+)
+
+// Close closes the rpc.Client and the underlying net.Conn.
+// Calls still waiting for a reply return rpc.ErrShutdown.
+func (d *RPCDuplex) Close() error {
+	return d.Client.Close()
+}
+
+// Done returns a channel that is closed once the connection is lost: when either end closes
+// it, it fails, or it is hijacked.
+func (d *RPCDuplex) Done() <-chan struct{} {
+	return d.mux.done
+}
+
+// Shutdown gracefully closes the duplex. New calls are refused with rpc.ErrShutdown
+// straight away, and the server stops reading requests: those not read yet are dropped,
+// and fail at the peer once the duplex is closed. Calls already in flight, both those made
+// by this end and those it is serving, are given until ctx is done to complete.
+// The duplex is then closed. If ctx is done first, the remaining calls are aborted
+// and ctx.Err() is returned.
+func (d *RPCDuplex) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	d.draining = true
+	d.mu.Unlock()
+	d.mux.requests.discard(io.EOF)
+
+	drained := make(chan struct{})
+	go func() {
+		d.inflight.Wait()
+		d.serving.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return d.Close()
+	case <-ctx.Done():
+		d.Close()
+		return ctx.Err()
+	}
+}
+
+// beginCall registers an outgoing call, failing if the duplex is shutting down.
+// Every successful beginCall must be paired with an endCall.
+func (d *RPCDuplex) beginCall() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.draining {
+		return rpc.ErrShutdown
+	}
+	d.inflight.Add(1)
+	return nil
+}
+
+func (d *RPCDuplex) endCall() {
+	d.inflight.Done()
+}
+
+// beginServe registers a request read by the server, reporting false if the duplex is
+// shutting down. Every successful beginServe must be paired with an endServe.
+func (d *RPCDuplex) beginServe() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.draining {
+		return false
+	}
+	d.serving.Add(1)
+	return true
+}
+
+func (d *RPCDuplex) endServe() {
+	d.serving.Done()
+}
+
+func (d *RPCDuplex) isDraining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
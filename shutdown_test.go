@@ -0,0 +1,100 @@
+package rpcmux
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/rpc"
+	"testing"
+	"time"
+)
+
+// Sleeper is a service whose calls take a given time.
+type Sleeper struct {
+	started chan struct{}
+}
+
+func (s *Sleeper) Sleep(d time.Duration, _ *struct{}) error {
+	if s.started != nil {
+		s.started <- struct{}{}
+	}
+	time.Sleep(d)
+	return nil
+}
+
+func newSleeperPipe(t *testing.T) (server, client *RPCDuplex, started chan struct{}) {
+	t.Helper()
+	connA, connB := net.Pipe()
+	server, client = NewRPCDuplex(connA), NewRPCDuplex(connB)
+	t.Cleanup(func() { client.Close() })
+
+	started = make(chan struct{}, 1)
+	if err := server.Register(&Sleeper{started: started}); err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+	return server, client, started
+}
+
+func TestShutdownWaitsForServedCalls(t *testing.T) {
+	server, client, started := newSleeperPipe(t)
+
+	errc := make(chan error, 1)
+	go func() { errc <- client.Call("Sleeper.Sleep", 100*time.Millisecond, &struct{}{}) }()
+	<-started
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("call in flight during Shutdown: %v", err)
+	}
+	if err := client.Call("Sleeper.Sleep", time.Duration(0), &struct{}{}); err == nil {
+		t.Error("call after Shutdown succeeded")
+	}
+}
+
+func TestShutdownRefusesNewCalls(t *testing.T) {
+	_, client, _ := newSleeperPipe(t)
+
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if err := client.Call("Sleeper.Sleep", time.Duration(0), &struct{}{}); !errors.Is(err, rpc.ErrShutdown) {
+		t.Errorf("Call after Shutdown = %v, want rpc.ErrShutdown", err)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	server, client, started := newSleeperPipe(t)
+
+	go client.Call("Sleeper.Sleep", time.Second, &struct{}{})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+// TestServerDisconnectMidCall closes the connection of the server while it handles a call,
+// before the response is written.
+func TestServerDisconnectMidCall(t *testing.T) {
+	server, client, started := newSleeperPipe(t)
+
+	errc := make(chan error, 1)
+	go func() { errc <- client.Call("Sleeper.Sleep", time.Second, &struct{}{}) }()
+	<-started
+	server.Conn.Close()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			t.Errorf("Call = %v, want io.ErrUnexpectedEOF or io.EOF", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Call still blocked after the server disconnected")
+	}
+}
@@ -0,0 +1,21 @@
+package rpcmux
+
+import "golang.org/x/net/proxy"
+
+// DialThroughSOCKS5 connects to targetAddr through the SOCKS5 proxy at socks5Addr,
+// authenticating with auth if it is not nil, and returns the connection as a RPCDuplex.
+func DialThroughSOCKS5(socks5Addr, targetAddr string, auth *proxy.Auth, opts ...Option) (*RPCDuplex, error) {
+	dialer, err := proxy.SOCKS5("tcp", socks5Addr, auth, proxy.Direct)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialer.Dial("tcp", targetAddr)
+	if err != nil {
+		return nil, err
+	}
+	d, err := newRPCDuplex(conn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
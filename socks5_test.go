@@ -0,0 +1,128 @@
+package rpcmux
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"golang.org/x/net/proxy"
+)
+
+// socks5Proxy returns the address of a SOCKS5 proxy serving CONNECT requests, requiring the
+// credentials user and password unless user is empty.
+func socks5Proxy(t *testing.T, user, password string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveSOCKS5(conn, user, password)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func serveSOCKS5(conn net.Conn, user, password string) {
+	defer conn.Close()
+	// read returns the next n bytes, or zeros once reading failed.
+	var err error
+	read := func(n int) []byte {
+		b := make([]byte, n)
+		if err == nil {
+			_, err = io.ReadFull(conn, b)
+		}
+		return b
+	}
+
+	read(int(read(2)[1])) // version, number of methods, methods
+	if err != nil {
+		return
+	}
+	if user == "" {
+		conn.Write([]byte{5, 0})
+	} else {
+		conn.Write([]byte{5, 2})
+		u := string(read(int(read(2)[1]))) // version, length, user
+		p := string(read(int(read(1)[0])))
+		if err != nil {
+			return
+		}
+		if u != user || p != password {
+			conn.Write([]byte{1, 1})
+			return
+		}
+		conn.Write([]byte{1, 0})
+	}
+
+	req := read(4) // version, command, reserved, address type
+	if err != nil || req[1] != 1 {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		host = net.IP(read(4)).String()
+	case 4:
+		host = net.IP(read(16)).String()
+	case 3:
+		host = string(read(int(read(1)[0])))
+	}
+	port := read(2)
+	if err != nil {
+		return
+	}
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1]))))
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+func TestDialThroughSOCKS5(t *testing.T) {
+	dl := listenTCP(t)
+	serveAccepted(t, dl)
+
+	for _, c := range []struct {
+		name           string
+		user, password string
+		auth           *proxy.Auth
+	}{
+		{"no auth", "", "", nil},
+		{"auth", "anto", "secret", &proxy.Auth{User: "anto", Password: "secret"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			d, err := DialThroughSOCKS5(socks5Proxy(t, c.user, c.password), dl.Addr().String(), c.auth)
+			if err != nil {
+				t.Fatalf("DialThroughSOCKS5: %v", err)
+			}
+			defer d.Close()
+			var reply Person
+			if err := d.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply); err != nil || reply.Name != "Anto" {
+				t.Errorf("Call through the proxy = %+v, %v", reply, err)
+			}
+		})
+	}
+}
+
+func TestDialThroughSOCKS5Refused(t *testing.T) {
+	dl := listenTCP(t)
+	addr := socks5Proxy(t, "anto", "secret")
+
+	if _, err := DialThroughSOCKS5(addr, dl.Addr().String(), &proxy.Auth{User: "anto", Password: "wrong"}); err == nil {
+		t.Error("DialThroughSOCKS5 with wrong credentials succeeded")
+	}
+	if _, err := DialThroughSOCKS5(addr, "127.0.0.1:1", &proxy.Auth{User: "anto", Password: "secret"}); err == nil {
+		t.Error("DialThroughSOCKS5 to a closed port succeeded")
+	}
+}
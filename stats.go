@@ -0,0 +1,89 @@
+package rpcmux
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyWindow is the number of most recent latencies kept per method to approximate percentiles.
+const latencyWindow = 1024
+
+// MethodStat summarises the calls made to a single "Service.Method" through RPCDuplex.Call.
+type MethodStat struct {
+	Calls, Errors uint64
+	TotalNanos    uint64
+	// P50Nanos and P99Nanos are approximated over the most recent calls.
+	P50Nanos, P99Nanos int64
+}
+
+// MethodStats returns a snapshot of the per method call statistics.
+func (d *RPCDuplex) MethodStats() map[string]MethodStat {
+	return d.stats.snapshot()
+}
+
+type methodStats struct {
+	mu      sync.Mutex
+	methods map[string]*methodStat
+}
+
+type methodStat struct {
+	calls, errors uint64
+	totalNanos    uint64
+	latencies     []int64 // ring buffer of the most recent latencies
+	next          int
+}
+
+func newMethodStats() *methodStats {
+	return &methodStats{methods: make(map[string]*methodStat)}
+}
+
+func (s *methodStats) record(method string, elapsed time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.methods[method]
+	if !ok {
+		m = &methodStat{latencies: make([]int64, 0, latencyWindow)}
+		s.methods[method] = m
+	}
+	m.calls++
+	if err != nil {
+		m.errors++
+	}
+	m.totalNanos += uint64(elapsed)
+
+	if len(m.latencies) < cap(m.latencies) {
+		m.latencies = append(m.latencies, int64(elapsed))
+		return
+	}
+	m.latencies[m.next] = int64(elapsed)
+	m.next = (m.next + 1) % len(m.latencies)
+}
+
+func (s *methodStats) snapshot() map[string]MethodStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]MethodStat, len(s.methods))
+	for name, m := range s.methods {
+		sorted := append([]int64(nil), m.latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		out[name] = MethodStat{
+			Calls:      m.calls,
+			Errors:     m.errors,
+			TotalNanos: m.totalNanos,
+			P50Nanos:   percentile(sorted, 50),
+			P99Nanos:   percentile(sorted, 99),
+		}
+	}
+	return out
+}
+
+// percentile returns the p-th percentile of the ascending sorted latencies.
+func percentile(sorted []int64, p int) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[(len(sorted)-1)*p/100]
+}
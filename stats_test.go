@@ -0,0 +1,57 @@
+package rpcmux
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMethodStatsPercentiles(t *testing.T) {
+	s := newMethodStats()
+	var total time.Duration
+	for i := 1; i <= latencyWindow; i++ {
+		var err error
+		if i%4 == 0 {
+			err = errors.New("boom")
+		}
+		s.record("S.M", time.Duration(i), err)
+		total += time.Duration(i)
+	}
+
+	got := s.snapshot()["S.M"]
+	want := MethodStat{Calls: latencyWindow, Errors: latencyWindow / 4, TotalNanos: uint64(total), P50Nanos: 512, P99Nanos: 1013}
+	if got != want {
+		t.Errorf("snapshot = %+v, want %+v", got, want)
+	}
+
+	// Overwrite the oldest half of the ring: latencies 1 to 512 are forgotten.
+	for i := 0; i < latencyWindow/2; i++ {
+		s.record("S.M", 5000, nil)
+		total += 5000
+	}
+	got = s.snapshot()["S.M"]
+	want = MethodStat{Calls: latencyWindow * 3 / 2, Errors: latencyWindow / 4, TotalNanos: uint64(total), P50Nanos: 1024, P99Nanos: 5000}
+	if got != want {
+		t.Errorf("snapshot once the ring wrapped = %+v, want %+v", got, want)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	for _, c := range []struct {
+		sorted []int64
+		p      int
+		want   int64
+	}{
+		{nil, 50, 0},
+		{[]int64{7}, 50, 7},
+		{[]int64{7}, 99, 7},
+		{[]int64{1, 2}, 50, 1},
+		{[]int64{1, 2}, 99, 1},
+		{[]int64{1, 2, 3}, 50, 2},
+		{[]int64{1, 2, 3}, 99, 2},
+	} {
+		if got := percentile(c.sorted, c.p); got != c.want {
+			t.Errorf("percentile(%v, %d) = %d, want %d", c.sorted, c.p, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Package sync provides a WaitGroup shared by processes connected over RPCDuplex.
+//
+// One end serves a Server holding the counter of every named WaitGroup; the other ends add
+// to, mark done and wait for them with RemoteWaitGroup. This lets a coordinator fan jobs out
+// to workers in other processes and wait until all of them are done.
+package sync
+
+import (
+	"context"
+	"errors"
+	"net/rpc"
+	"sync"
+	"time"
+
+	rpcmux "github.com/atang152/test_duplex"
+)
+
+const (
+	// serviceName is the name the Server is registered under.
+	serviceName = "WaitGroup"
+
+	// pollTimeout is how long a Wait call waits on the server before the client calls again.
+	pollTimeout = 10 * time.Second
+)
+
+// ErrNegativeCounter is returned when a WaitGroup counter would go negative.
+var ErrNegativeCounter = errors.New("sync: negative WaitGroup counter")
+
+// AddRequest is the argument of WaitGroup.Add.
+type AddRequest struct {
+	Name  string
+	Delta int
+}
+
+// Server holds the counter of every named WaitGroup.
+type Server struct {
+	mu     sync.Mutex
+	groups map[string]*group
+}
+
+type group struct {
+	n    int
+	zero chan struct{} // closed when n drops to zero
+}
+
+// NewServer returns a Server with every WaitGroup at zero.
+func NewServer() *Server {
+	return &Server{groups: make(map[string]*group)}
+}
+
+// Serve registers s on d so that the peer at the other end can use its WaitGroups.
+// The same Server is served on the duplex of every peer sharing the WaitGroups.
+func (s *Server) Serve(d *rpcmux.RPCDuplex) error {
+	return d.RegisterName(serviceName, &waitGroupService{s})
+}
+
+// waitGroupService is the RPC facade of a Server.
+type waitGroupService struct {
+	s *Server
+}
+
+// Add adds req.Delta to the counter of req.Name, releasing its waiters when it drops to zero.
+func (w *waitGroupService) Add(req AddRequest, _ *struct{}) error {
+	w.s.mu.Lock()
+	defer w.s.mu.Unlock()
+
+	g := w.s.groups[req.Name]
+	if g == nil {
+		g = &group{zero: make(chan struct{})}
+	}
+	if g.n+req.Delta < 0 {
+		return ErrNegativeCounter
+	}
+	g.n += req.Delta
+	if g.n == 0 {
+		close(g.zero)
+		delete(w.s.groups, req.Name)
+		return nil
+	}
+	w.s.groups[req.Name] = g
+	return nil
+}
+
+// Wait waits for the counter of name to drop to zero and reports whether it did within pollTimeout.
+func (w *waitGroupService) Wait(name string, done *bool) error {
+	w.s.mu.Lock()
+	g := w.s.groups[name]
+	w.s.mu.Unlock()
+
+	if g == nil {
+		*done = true
+		return nil
+	}
+	timer := time.NewTimer(pollTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-g.zero:
+		*done = true
+	case <-timer.C:
+		*done = false
+	}
+	return nil
+}
+
+// RemoteWaitGroup is a named WaitGroup held by the Server served at the other end of a RPCDuplex.
+type RemoteWaitGroup struct {
+	d    *rpcmux.RPCDuplex
+	name string
+}
+
+// NewRemoteWaitGroup returns the RemoteWaitGroup name of the Server served at the other end of d.
+func NewRemoteWaitGroup(d *rpcmux.RPCDuplex, name string) *RemoteWaitGroup {
+	return &RemoteWaitGroup{d: d, name: name}
+}
+
+// Add adds delta, which may be negative, to the counter.
+// It returns ErrNegativeCounter, leaving the counter unchanged, if the counter would go negative.
+func (wg *RemoteWaitGroup) Add(delta int) error {
+	err := wg.d.Call(serviceName+".Add", AddRequest{Name: wg.name, Delta: delta}, &struct{}{})
+	if err == rpc.ServerError(ErrNegativeCounter.Error()) {
+		return ErrNegativeCounter
+	}
+	return err
+}
+
+// Done decrements the counter by one.
+func (wg *RemoteWaitGroup) Done() error {
+	return wg.Add(-1)
+}
+
+// Wait blocks until the counter is zero or ctx is done.
+func (wg *RemoteWaitGroup) Wait(ctx context.Context) error {
+	for {
+		var done bool
+		if err := wg.d.CallContext(ctx, serviceName+".Wait", wg.name, &done); err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/atang152/test_duplex/internal/rpctest"
+)
+
+func TestRemoteWaitGroup(t *testing.T) {
+	s := NewServer()
+	coordinator := NewRemoteWaitGroup(rpctest.Serve(t, s.Serve), "jobs")
+	worker := NewRemoteWaitGroup(rpctest.Serve(t, s.Serve), "jobs")
+
+	if err := coordinator.Add(2); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	waited := make(chan error, 1)
+	go func() { waited <- coordinator.Wait(context.Background()) }()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-waited:
+			t.Fatalf("Wait returned %v before the jobs were done", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+		if err := worker.Done(); err != nil {
+			t.Fatalf("Done: %v", err)
+		}
+	}
+	select {
+	case err := <-waited:
+		if err != nil {
+			t.Errorf("Wait: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not return once the jobs were done")
+	}
+
+	if err := worker.Done(); err != ErrNegativeCounter {
+		t.Errorf("Done at zero = %v, want ErrNegativeCounter", err)
+	}
+}
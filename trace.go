@@ -0,0 +1,61 @@
+package rpcmux
+
+import (
+	"sync"
+	"time"
+)
+
+// CallTraceEntry is a single call recorded by WithCallTrace.
+type CallTraceEntry struct {
+	Time    time.Time     // when the call was started
+	Method  string        // the "Service.Method" that was called
+	Elapsed time.Duration // how long the call took to complete
+	Errored bool          // whether the call returned a non-nil error
+}
+
+// WithCallTrace keeps the last maxEntries calls made through RPCDuplex.Call in memory.
+// The recorded calls can be retrieved with DumpCallTrace.
+func WithCallTrace(maxEntries int) Option {
+	return func(d *RPCDuplex) {
+		if maxEntries > 0 {
+			d.trace = &callTrace{entries: make([]CallTraceEntry, 0, maxEntries)}
+		}
+	}
+}
+
+// DumpCallTrace returns the recorded calls, oldest first.
+// It returns nil if the RPCDuplex was not created WithCallTrace.
+func (d *RPCDuplex) DumpCallTrace() []CallTraceEntry {
+	if d.trace == nil {
+		return nil
+	}
+	return d.trace.dump()
+}
+
+// callTrace is a fixed size ring buffer of CallTraceEntry.
+type callTrace struct {
+	mu      sync.Mutex
+	entries []CallTraceEntry
+	next    int // index of the oldest entry once the buffer is full
+}
+
+func (t *callTrace) record(e CallTraceEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.entries) < cap(t.entries) {
+		t.entries = append(t.entries, e)
+		return
+	}
+	t.entries[t.next] = e
+	t.next = (t.next + 1) % len(t.entries)
+}
+
+func (t *callTrace) dump() []CallTraceEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]CallTraceEntry, 0, len(t.entries))
+	out = append(out, t.entries[t.next:]...)
+	return append(out, t.entries[:t.next]...)
+}
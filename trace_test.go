@@ -0,0 +1,65 @@
+package rpcmux
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCallTraceWrap(t *testing.T) {
+	const maxEntries = 4
+	for _, calls := range []int{0, 3, maxEntries, maxEntries + 1, 2*maxEntries + 3} {
+		tr := &callTrace{entries: make([]CallTraceEntry, 0, maxEntries)}
+		for i := 0; i < calls; i++ {
+			tr.record(CallTraceEntry{Method: fmt.Sprint(i)})
+		}
+
+		var want []string
+		for i := calls - maxEntries; i < calls; i++ {
+			if i >= 0 {
+				want = append(want, fmt.Sprint(i))
+			}
+		}
+		var got []string
+		for _, e := range tr.dump() {
+			got = append(got, e.Method)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("after %d calls, dump = %v, want %v", calls, got, want)
+		}
+	}
+}
+
+func TestDumpCallTrace(t *testing.T) {
+	a, b := newPipe(t, WithCallTrace(3))
+	if err := b.Register(Failer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var reply Person
+	for _, method := range []string{"Failer.Fail", "Failer.Fail", "RPCMethod.SayHello", "Failer.Fail", "RPCMethod.SayHello"} {
+		a.Call(method, Person{Name: "Anto"}, &reply)
+	}
+
+	entries := a.DumpCallTrace()
+	want := []struct {
+		method  string
+		errored bool
+	}{{"RPCMethod.SayHello", false}, {"Failer.Fail", true}, {"RPCMethod.SayHello", false}}
+	if len(entries) != len(want) {
+		t.Fatalf("DumpCallTrace returned %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if e.Method != want[i].method || e.Errored != want[i].errored {
+			t.Errorf("entry %d = %s, errored %v, want %s, errored %v", i, e.Method, e.Errored, want[i].method, want[i].errored)
+		}
+		if i > 0 && e.Time.Before(entries[i-1].Time) {
+			t.Errorf("entry %d started before entry %d", i, i-1)
+		}
+	}
+
+	untraced, _ := newPipe(t)
+	untraced.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply)
+	if entries := untraced.DumpCallTrace(); entries != nil {
+		t.Errorf("DumpCallTrace without WithCallTrace = %v, want nil", entries)
+	}
+}
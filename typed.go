@@ -0,0 +1,15 @@
+package rpcmux
+
+import "context"
+
+// TypedCall calls method with req and returns its reply, giving compile-time checking of
+// the request and response types. The call itself is made with CallContext.
+//
+// Req and Resp are not checked against the types of method before the call. A Req the peer
+// cannot decode makes the call fail with a rpc.ServerError. A reply that cannot be decoded
+// into a Resp makes it fail too, and, as with rpc.Client, shuts the client of d down.
+func TypedCall[Req, Resp any](ctx context.Context, d *RPCDuplex, method string, req Req) (Resp, error) {
+	var resp Resp
+	err := d.CallContext(ctx, method, req, &resp)
+	return resp, err
+}
@@ -0,0 +1,34 @@
+package rpcmux
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTypedCall(t *testing.T) {
+	a, _ := newPipe(t)
+
+	reply, err := TypedCall[Person, Person](context.Background(), a, "RPCMethod.SayHello", Person{Name: "Anto"})
+	if err != nil {
+		t.Fatalf("TypedCall: %v", err)
+	}
+	if reply.Name != "Anto" {
+		t.Errorf("TypedCall = %+v, want Anto", reply)
+	}
+}
+
+func TestTypedCallTypeMismatch(t *testing.T) {
+	a, _ := newPipe(t)
+
+	if _, err := TypedCall[int, Person](context.Background(), a, "RPCMethod.SayHello", 42); err == nil {
+		t.Error("TypedCall with a request of the wrong type succeeded")
+	}
+	// The server answers with an error, and the duplex is still usable afterwards.
+	if _, err := TypedCall[Person, Person](context.Background(), a, "RPCMethod.SayHello", Person{Name: "Anto"}); err != nil {
+		t.Errorf("TypedCall after a request of the wrong type: %v", err)
+	}
+
+	if _, err := TypedCall[Person, int](context.Background(), a, "RPCMethod.SayHello", Person{Name: "Anto"}); err == nil {
+		t.Error("TypedCall with a reply of the wrong type succeeded")
+	}
+}
@@ -0,0 +1,20 @@
+//go:build linux
+
+package rpcmux
+
+import "net"
+
+// NewRPCDuplexUnixAbstract connects to the Unix domain socket name in the Linux abstract namespace
+// and returns it as a RPCDuplex. Abstract sockets are not backed by a file, so they disappear with
+// the process that listens on them and never leave stale socket files behind.
+func NewRPCDuplexUnixAbstract(name string, opts ...Option) (*RPCDuplex, error) {
+	conn, err := net.Dial("unix", "@"+name)
+	if err != nil {
+		return nil, err
+	}
+	d, err := newRPCDuplex(conn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
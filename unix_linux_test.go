@@ -0,0 +1,38 @@
+//go:build linux
+
+package rpcmux
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+)
+
+// testSocketName returns a local socket name unique to t and to the test process.
+func testSocketName(t *testing.T) string {
+	return fmt.Sprintf("rpcmux-%d-%s", os.Getpid(), t.Name())
+}
+
+func TestNewRPCDuplexUnixAbstract(t *testing.T) {
+	name := testSocketName(t)
+	l, err := net.Listen("unix", "@"+name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveAccepted(t, ListenRPCDuplex(l))
+
+	d, err := NewRPCDuplexUnixAbstract(name)
+	if err != nil {
+		t.Fatalf("NewRPCDuplexUnixAbstract: %v", err)
+	}
+	defer d.Close()
+	var reply Person
+	if err := d.Call("RPCMethod.SayHello", Person{Name: "Anto"}, &reply); err != nil || reply.Name != "Anto" {
+		t.Errorf("Call = %+v, %v", reply, err)
+	}
+
+	if _, err := NewRPCDuplexUnixAbstract(name + "-absent"); err == nil {
+		t.Error("NewRPCDuplexUnixAbstract of a name nobody listens on succeeded")
+	}
+}
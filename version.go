@@ -0,0 +1,50 @@
+package rpcmux
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is the version of this library, following semantic versioning.
+const Version = "0.1.0"
+
+// ProtocolVersion identifies the wire format spoken by RPCDuplex. Each end sends it in the
+// hello frame that starts the connection, and the other end refuses other versions.
+// It must be bumped whenever a change makes the wire format incompatible with older peers.
+// Version 2 introduced the framing of messages.
+const ProtocolVersion = uint16(2)
+
+// ErrVersionMismatch is the error of the calls of a duplex whose peer speaks another
+// ProtocolVersion, or sent none. The connection is closed when it happens.
+var ErrVersionMismatch = errors.New("rpcmux: protocol version mismatch")
+
+// AssertMinVersion panics if Version is older than major.minor.patch.
+// Libraries built on rpcmux can call it from an init function to fail early
+// when linked against a version that lacks the features they rely on.
+func AssertMinVersion(major, minor, patch int) {
+	have := parseVersion(Version)
+	want := [3]int{major, minor, patch}
+	for i := range have {
+		if have[i] > want[i] {
+			return
+		}
+		if have[i] < want[i] {
+			panic(fmt.Sprintf("rpcmux: version %s is older than the required %d.%d.%d", Version, major, minor, patch))
+		}
+	}
+}
+
+// parseVersion splits a "major.minor.patch" version into its numeric parts.
+func parseVersion(v string) [3]int {
+	var parts [3]int
+	for i, s := range strings.SplitN(v, ".", 3) {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			panic(fmt.Sprintf("rpcmux: malformed version %q", v))
+		}
+		parts[i] = n
+	}
+	return parts
+}
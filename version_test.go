@@ -0,0 +1,81 @@
+package rpcmux
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestVersionMismatch makes the peer announce version 0xDEAD: calls must fail with
+// ErrVersionMismatch and the connection be closed.
+func TestVersionMismatch(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		first []byte
+	}{
+		{"other version", []byte{tagHello, 0, 0, 0, 2, 0xDE, 0xAD}},
+		{"no hello", []byte{tagRequest, 0, 0, 0, 1, 0}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			connA, connB := net.Pipe()
+			d := NewRPCDuplex(connA)
+			defer d.Close()
+			go connB.Write(tc.first)
+
+			errc := make(chan error, 1)
+			go func() {
+				var reply Person
+				errc <- d.Call("RPCMethod.SayHello", Person{}, &reply)
+			}()
+			select {
+			case err := <-errc:
+				if !errors.Is(err, ErrVersionMismatch) {
+					t.Errorf("Call = %v, want ErrVersionMismatch", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Call blocked")
+			}
+			if _, err := connB.Write([]byte{0}); err == nil {
+				t.Error("connection still open after a version mismatch")
+			}
+		})
+	}
+}
+
+func TestAssertMinVersion(t *testing.T) {
+	v := parseVersion(Version)
+	for _, tc := range []struct {
+		name      string
+		min       [3]int
+		wantPanic bool
+	}{
+		{"equal", v, false},
+		{"older minor", [3]int{v[0], v[1] - 1, v[2] + 1}, false},
+		{"older version", [3]int{0, 0, 0}, false},
+		{"newer patch", [3]int{v[0], v[1], v[2] + 1}, true},
+		{"newer minor", [3]int{v[0], v[1] + 1, 0}, true},
+		{"newer major", [3]int{v[0] + 1, 0, 0}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); (r != nil) != tc.wantPanic {
+					t.Errorf("AssertMinVersion(%d, %d, %d) with version %s: panic %v, want panic %v", tc.min[0], tc.min[1], tc.min[2], Version, r, tc.wantPanic)
+				}
+			}()
+			AssertMinVersion(tc.min[0], tc.min[1], tc.min[2])
+		})
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	if got := parseVersion("1.22.3"); got != [3]int{1, 22, 3} {
+		t.Errorf("parseVersion(1.22.3) = %v", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("parseVersion of a malformed version did not panic")
+		}
+	}()
+	parseVersion("1.x.3")
+}
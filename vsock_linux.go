@@ -0,0 +1,68 @@
+//go:build linux
+
+package rpcmux
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// NewRPCDuplexVsock connects to port on the virtual machine or hypervisor identified by cid
+// over AF_VSOCK, and returns the connection as a RPCDuplex. No TCP/IP stack is needed
+// between the guest and the host.
+func NewRPCDuplexVsock(cid, port uint32, opts ...Option) (*RPCDuplex, error) {
+	fd, err := unix.Socket(unix.AF_VSOCK, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	if err := unix.Connect(fd, &unix.SockaddrVM{CID: cid, Port: port}); err != nil {
+		unix.Close(fd)
+		return nil, os.NewSyscallError("connect", err)
+	}
+	local := vsockAddr{}
+	if sa, err := unix.Getsockname(fd); err == nil {
+		if vm, ok := sa.(*unix.SockaddrVM); ok {
+			local = vsockAddr{CID: vm.CID, Port: vm.Port}
+		}
+	}
+	// Non-blocking mode lets the os.File use the runtime poller, which provides deadlines.
+	if err := unix.SetNonblock(fd, true); err != nil {
+		unix.Close(fd)
+		return nil, os.NewSyscallError("setnonblock", err)
+	}
+
+	remote := vsockAddr{CID: cid, Port: port}
+	conn := &vsockConn{
+		File:   os.NewFile(uintptr(fd), remote.String()),
+		local:  local,
+		remote: remote,
+	}
+	d, err := newRPCDuplex(conn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// vsockAddr is the address of one end of an AF_VSOCK connection.
+type vsockAddr struct {
+	CID, Port uint32
+}
+
+func (a vsockAddr) Network() string { return "vsock" }
+func (a vsockAddr) String() string  { return fmt.Sprintf("vm(%d):%d", a.CID, a.Port) }
+
+// vsockConn is a net.Conn over a connected AF_VSOCK socket. net.FileConn does not
+// recognise AF_VSOCK addresses, so the socket is used directly through an os.File.
+type vsockConn struct {
+	*os.File
+	local, remote vsockAddr
+}
+
+var _ net.Conn = (*vsockConn)(nil)
+
+func (c *vsockConn) LocalAddr() net.Addr  { return c.local }
+func (c *vsockConn) RemoteAddr() net.Addr { return c.remote }
@@ -0,0 +1,92 @@
+package rpcmux
+
+import (
+	"io"
+	"text/template"
+)
+
+// ExportWiresharkDissector writes to w a Lua script implementing a Wireshark dissector for the
+// frames of RPCDuplex, showing the tag, length and payload of each frame and the version
+// carried by hello frames. The payloads, gob-encoded net/rpc messages, are shown as bytes.
+//
+// Load the script with "wireshark -X lua_script:rpcmux.lua", or from the Wireshark plugins
+// directory, then use Decode As on the TCP port of the duplex. Connections using a PROXY
+// protocol header or WithChecksumming are not dissected.
+func ExportWiresharkDissector(w io.Writer) error {
+	return dissectorTemplate.Execute(w, struct {
+		ProtocolVersion          uint16
+		Hello, Request, Response byte
+		MaxPayload               int
+	}{ProtocolVersion, tagHello, tagRequest, tagResponse, maxFramePayload})
+}
+
+var dissectorTemplate = template.Must(template.New("rpcmux.lua").Parse(`-- Wireshark dissector for RPCDuplex frames, protocol version {{.ProtocolVersion}}.
+-- Generated by rpcmux.ExportWiresharkDissector.
+--
+-- frame: | tag (1 byte) | length (4 bytes, big-endian) | payload (length bytes) |
+
+local rpcmux = Proto("rpcmux", "RPCDuplex")
+
+local TAG_HELLO = {{.Hello}}
+local TAG_REQUEST = {{.Request}}
+local TAG_RESPONSE = {{.Response}}
+local HEADER_LEN = 5
+local MAX_PAYLOAD = {{.MaxPayload}}
+local PROTOCOL_VERSION = {{.ProtocolVersion}}
+
+local tag_names = {
+	[TAG_HELLO] = "Hello",
+	[TAG_REQUEST] = "Request",
+	[TAG_RESPONSE] = "Response",
+}
+
+local f_tag = ProtoField.uint8("rpcmux.tag", "Tag", base.HEX, tag_names)
+local f_length = ProtoField.uint32("rpcmux.length", "Length", base.DEC)
+local f_version = ProtoField.uint16("rpcmux.version", "Protocol version", base.DEC)
+local f_payload = ProtoField.bytes("rpcmux.payload", "Payload")
+rpcmux.fields = { f_tag, f_length, f_version, f_payload }
+
+local e_bad_frame = ProtoExpert.new("rpcmux.bad_frame", "Malformed frame", expert.group.MALFORMED, expert.severity.ERROR)
+local e_version = ProtoExpert.new("rpcmux.version_mismatch", "Unexpected protocol version", expert.group.PROTOCOL, expert.severity.WARN)
+rpcmux.experts = { e_bad_frame, e_version }
+
+local function frame_len(tvb, pinfo, offset)
+	return HEADER_LEN + tvb(offset + 1, 4):uint()
+end
+
+local function dissect_frame(tvb, pinfo, tree)
+	local tag = tvb(0, 1):uint()
+	local length = tvb(1, 4):uint()
+	local name = tag_names[tag] or "Unknown"
+
+	pinfo.cols.protocol = "RPCMUX"
+	pinfo.cols.info:append(" " .. name .. " (" .. length .. " bytes)")
+
+	local subtree = tree:add(rpcmux, tvb(0, HEADER_LEN + length), "RPCDuplex " .. name .. " frame")
+	local tag_item = subtree:add(f_tag, tvb(0, 1))
+	local length_item = subtree:add(f_length, tvb(1, 4))
+	if tag_names[tag] == nil then
+		tag_item:add_proto_expert_info(e_bad_frame, "Unknown tag")
+	end
+	if length > MAX_PAYLOAD then
+		length_item:add_proto_expert_info(e_bad_frame, "Payload longer than " .. MAX_PAYLOAD .. " bytes")
+	end
+
+	if tag == TAG_HELLO and length >= 2 then
+		local version_item = subtree:add(f_version, tvb(HEADER_LEN, 2))
+		if tvb(HEADER_LEN, 2):uint() ~= PROTOCOL_VERSION then
+			version_item:add_proto_expert_info(e_version)
+		end
+	elseif length > 0 then
+		subtree:add(f_payload, tvb(HEADER_LEN, length))
+	end
+	return HEADER_LEN + length
+end
+
+function rpcmux.dissector(tvb, pinfo, tree)
+	dissect_tcp_pdus(tvb, tree, HEADER_LEN, frame_len, dissect_frame)
+	return tvb:len()
+end
+
+DissectorTable.get("tcp.port"):add_for_decode_as(rpcmux)
+`))
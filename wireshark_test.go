@@ -0,0 +1,28 @@
+package rpcmux
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExportWiresharkDissector(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportWiresharkDissector(&buf); err != nil {
+		t.Fatalf("ExportWiresharkDissector: %v", err)
+	}
+	script := buf.String()
+	for _, want := range []string{
+		`Proto("rpcmux", "RPCDuplex")`,
+		fmt.Sprintf("local PROTOCOL_VERSION = %d", ProtocolVersion),
+		fmt.Sprintf("local TAG_REQUEST = %d", tagRequest),
+		fmt.Sprintf("local TAG_RESPONSE = %d", tagResponse),
+		fmt.Sprintf("local MAX_PAYLOAD = %d", maxFramePayload),
+		"dissect_tcp_pdus(",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("dissector does not contain %q", want)
+		}
+	}
+}